@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/obby/content-tracker/internal/interceptors"
 	"github.com/obby/content-tracker/internal/server"
 	"github.com/obby/content-tracker/internal/tracker"
 	pb "github.com/obby/content-tracker/proto/generated"
 	"google.golang.org/grpc"
 )
 
+// vacuumInterval is how often runVacuumLoop calls ContentTracker.Vacuum.
+const vacuumInterval = 1 * time.Hour
+
 func main() {
 	// Get database path from environment or use default
 	dbPath := os.Getenv("DB_PATH")
@@ -29,8 +36,37 @@ func main() {
 	// Start worker pool
 	ct.StartWorkerPool()
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	// How often a tracked version stores a full content snapshot rather
+	// than a diff-only version; see database.DB.SetSnapshotInterval.
+	snapshotInterval := 10
+	if v := os.Getenv("SNAPSHOT_INTERVAL"); v != "" {
+		fmt.Sscanf(v, "%d", &snapshotInterval)
+	}
+	ct.SetSnapshotInterval(snapshotInterval)
+
+	// Periodically prune diffs and blobs old enough that VACUUM_RETENTION
+	// says we no longer need them.
+	vacuumRetention := 30 * 24 * time.Hour
+	if v := os.Getenv("VACUUM_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			vacuumRetention = d
+		} else {
+			log.Printf("Unrecognized VACUUM_RETENTION %q, keeping default of %s", v, vacuumRetention)
+		}
+	}
+	go runVacuumLoop(ct, vacuumRetention)
+
+	// Create gRPC server with the shared logging/recovery/metrics/timeout/
+	// auth interceptor chain
+	interceptorCfg := interceptors.Config{
+		DefaultTimeout: 30 * time.Second,
+	}
+	if tokens := os.Getenv("AUTH_TOKENS"); tokens != "" {
+		interceptorCfg.Authenticator = &interceptors.StaticTokenAuthenticator{
+			Tokens: parseTokens(tokens),
+		}
+	}
+	grpcServer := grpc.NewServer(interceptors.ServerOptions(interceptorCfg)...)
 
 	// Register ContentTracker service
 	contentTrackerServer := server.NewContentTrackerServer(ct)
@@ -48,6 +84,13 @@ func main() {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
+	// Serve Prometheus metrics on a separate admin port
+	adminPort := 50062
+	if portStr := os.Getenv("ADMIN_PORT"); portStr != "" {
+		fmt.Sscanf(portStr, "%d", &adminPort)
+	}
+	go interceptors.ServeMetrics(fmt.Sprintf(":%d", adminPort), grpcServer)
+
 	log.Printf("Content Tracker Service listening on :%d", port)
 
 	// Serve
@@ -56,3 +99,30 @@ func main() {
 	}
 }
 
+// runVacuumLoop calls ct.Vacuum(retention) every vacuumInterval until the
+// process exits; failures are logged and retried next tick rather than
+// treated as fatal, since a stale Vacuum just means the DB grows a bit
+// more than it needs to.
+func runVacuumLoop(ct *tracker.ContentTracker, retention time.Duration) {
+	ticker := time.NewTicker(vacuumInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := ct.Vacuum(context.Background(), retention); err != nil {
+			log.Printf("Vacuum failed: %v", err)
+		}
+	}
+}
+
+// parseTokens splits a comma-separated AUTH_TOKENS env var into the set
+// accepted by StaticTokenAuthenticator.
+func parseTokens(raw string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			tokens[t] = true
+		}
+	}
+	return tokens
+}
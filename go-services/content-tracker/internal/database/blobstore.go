@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// blobAlgoZstd is the only value currently stored in blobs.algo; it's
+// still recorded per-row so a future codec change doesn't have to rewrite
+// history to stay readable.
+const blobAlgoZstd = "zstd"
+
+// zstdEncoder and zstdDecoder are package-level singletons: both EncodeAll
+// and DecodeAll are documented as safe for concurrent use, so there's no
+// need to pool them the way hashPool pools sha256 hashers in tracker.go.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// putBlob compresses content and stores it under hash (the SHA-256
+// ContentTracker already computed via CalculateHash), so identical content
+// shared across files or versions is stored once. A blob that already
+// exists under hash is left untouched.
+func (db *DB) putBlob(ctx context.Context, tx *sql.Tx, hash, content string) error {
+	var exists int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM blobs WHERE hash = ?`, hash).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check existing blob %s: %w", hash, err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	compressed := zstdEncoder.EncodeAll([]byte(content), nil)
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO blobs (hash, compressed, algo, orig_size)
+		VALUES (?, ?, ?, ?)
+	`, hash, compressed, blobAlgoZstd, len(content))
+	if err != nil {
+		return fmt.Errorf("failed to insert blob %s: %w", hash, err)
+	}
+	return nil
+}
+
+// getBlob retrieves and decompresses the blob stored under hash.
+func (db *DB) getBlob(ctx context.Context, hash string) (string, error) {
+	var compressed []byte
+	var algo string
+	err := db.conn.QueryRowContext(ctx, `SELECT compressed, algo FROM blobs WHERE hash = ?`, hash).Scan(&compressed, &algo)
+	if err != nil {
+		return "", fmt.Errorf("failed to load blob %s: %w", hash, err)
+	}
+	if algo != blobAlgoZstd {
+		return "", fmt.Errorf("blob %s: unsupported compression algo %q", hash, algo)
+	}
+
+	raw, err := zstdDecoder.DecodeAll(compressed, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress blob %s: %w", hash, err)
+	}
+	return string(raw), nil
+}
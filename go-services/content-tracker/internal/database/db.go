@@ -3,14 +3,23 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
 )
 
+// defaultSnapshotInterval is how often InsertFileVersion stores a full
+// content snapshot when DB.snapshotInterval hasn't been overridden: every
+// 10th version (and always the first) gets one; the versions in between
+// store only a diff against the previous version.
+const defaultSnapshotInterval = 10
+
 // DB wraps SQLite database connection
 type DB struct {
-	conn *sql.DB
+	conn             *sql.DB
+	snapshotInterval int
 }
 
 // NewDB creates a new database connection
@@ -31,7 +40,110 @@ func NewDB(dbPath string) (*DB, error) {
 		return nil, err
 	}
 
-	return &DB{conn: conn}, nil
+	db := &DB{conn: conn, snapshotInterval: defaultSnapshotInterval}
+
+	if err := db.initBlobStore(); err != nil {
+		return nil, err
+	}
+
+	// Search indexing is a secondary concern of query-service's, not
+	// something a failure here should block tracking over, so this is
+	// logged rather than returned; see initSearchIndex.
+	if err := db.initSearchIndex(); err != nil {
+		log.WithError(err).Warn("search index initialization failed")
+	}
+
+	return db, nil
+}
+
+// SetSnapshotInterval overrides how often InsertFileVersion stores a full
+// content snapshot rather than a diff-only version; see defaultSnapshotInterval.
+// n <= 0 disables the interval, so every version is stored as a full
+// snapshot (matching the table's original, pre-blob-store behavior).
+func (db *DB) SetSnapshotInterval(n int) {
+	db.snapshotInterval = n
+}
+
+// initBlobStore creates the blobs table and the file_versions.is_snapshot
+// column used to key into it, if they don't already exist. Unlike the rest
+// of file_versions (created by the Python backend), these are new with the
+// blob store and so are created here rather than assumed to pre-exist.
+func (db *DB) initBlobStore() error {
+	_, err := db.conn.Exec(`
+		CREATE TABLE IF NOT EXISTS blobs (
+			hash TEXT PRIMARY KEY,
+			compressed BLOB NOT NULL,
+			algo TEXT NOT NULL,
+			orig_size INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create blobs table: %w", err)
+	}
+
+	var hasColumn int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('file_versions') WHERE name = 'is_snapshot'
+	`).Scan(&hasColumn)
+	if err != nil {
+		return fmt.Errorf("failed to inspect file_versions columns: %w", err)
+	}
+	if hasColumn == 0 {
+		// Existing rows predate the blob store and still carry their full
+		// content inline, so they default to is_snapshot = 1 (no diff to
+		// replay, which is correct: their content column already has it).
+		if _, err := db.conn.Exec(`ALTER TABLE file_versions ADD COLUMN is_snapshot INTEGER NOT NULL DEFAULT 1`); err != nil {
+			return fmt.Errorf("failed to add file_versions.is_snapshot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// searchIndexSchema creates file_versions_fts, the full-text index behind
+// query-service's SearchContent/SuggestTerms. It deliberately does NOT use
+// fts5's "content=file_versions, content_rowid=id" external-content mode:
+// that mode stores only the token index itself and re-reads the source text
+// from file_versions.content on every snippet()/highlight() call, which
+// would force content to stay fully populated for every version (snapshot
+// or diff-only) just to keep search working. Declaring it as a standalone
+// fts5 table instead makes it carry its own full-text copy, populated once
+// by InsertFileVersion from the in-memory content it already has before
+// diffing/compression, independently of whatever file_versions.content ends
+// up holding afterward.
+const searchIndexSchema = `CREATE VIRTUAL TABLE IF NOT EXISTS file_versions_fts USING fts5(file_path, content)`
+
+// initSearchIndex ensures file_versions_fts and its autocomplete vocab view
+// exist. Query-service's own DB.initFTS creates the identical schema, so
+// whichever service starts first wins; IF NOT EXISTS makes the other a
+// no-op rather than a schema conflict.
+func (db *DB) initSearchIndex() error {
+	if _, err := db.conn.Exec(searchIndexSchema); err != nil {
+		return fmt.Errorf("failed to create file_versions_fts: %w", err)
+	}
+
+	if _, err := db.conn.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS file_versions_vocab
+		USING fts5vocab(file_versions_fts, 'row')
+	`); err != nil {
+		return fmt.Errorf("failed to create file_versions_vocab: %w", err)
+	}
+
+	return nil
+}
+
+// indexForSearch inserts content into file_versions_fts under rowid
+// versionID, keeping it in lockstep with the file_versions row of the same
+// id so query-service's "JOIN file_versions_fts fts ON fv.id = fts.rowid"
+// keeps matching the right row. Errors are logged rather than propagated:
+// search indexing failing (e.g. file_versions_fts doesn't exist yet because
+// neither service has initialized it) shouldn't block tracking itself.
+func (db *DB) indexForSearch(ctx context.Context, tx *sql.Tx, versionID int64, filePath, content string) {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO file_versions_fts(rowid, file_path, content) VALUES (?, ?, ?)
+	`, versionID, filePath, content); err != nil {
+		log.WithError(err).WithField("file_path", filePath).Warn("failed to index file version for search")
+	}
 }
 
 // GetPreviousHash gets the previous hash and version ID for a file
@@ -57,37 +169,94 @@ func (db *DB) GetPreviousHash(ctx context.Context, filePath string) (string, int
 	return hash, versionID, nil
 }
 
-// InsertFileVersion inserts a new file version
+// InsertFileVersion inserts a new file version. Every snapshotInterval-th
+// version (and always the file's first version, since there's nothing yet
+// to diff against) additionally stores content as a full compressed blob
+// keyed by hash, deduplicating identical content across files/versions; the
+// content column itself is left empty for every other version, which is the
+// whole point of the blob store (storing the full body again inline would
+// defeat the diff-only storage savings). query-service's SearchContent
+// still needs full text for every version regardless, so it's indexed into
+// file_versions_fts here instead, from the in-memory content this call
+// already has before it's discarded/diffed away; see indexForSearch.
 func (db *DB) InsertFileVersion(ctx context.Context, filePath, hash, content string, size int64) (int64, error) {
-	query := `
-		INSERT INTO file_versions (file_path, content_hash, content, size, timestamp)
-		VALUES (?, ?, ?, ?, ?)
-	`
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin file version transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var priorVersions int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM file_versions WHERE file_path = ?`, filePath).Scan(&priorVersions)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count prior versions of %s: %w", filePath, err)
+	}
+	isSnapshot := priorVersions == 0 || db.snapshotInterval <= 0 || priorVersions%db.snapshotInterval == 0
+
+	storedContent := ""
+	if isSnapshot {
+		storedContent = content
+		if err := db.putBlob(ctx, tx, hash, content); err != nil {
+			return 0, err
+		}
+	}
 
-	result, err := db.conn.ExecContext(ctx, query, filePath, hash, content, size, time.Now().Unix())
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO file_versions (file_path, content_hash, content, size, timestamp, is_snapshot)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, filePath, hash, storedContent, size, time.Now().Unix(), isSnapshot)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to insert file version: %w", err)
 	}
 
 	versionID, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to get inserted file version id: %w", err)
+	}
+
+	db.indexForSearch(ctx, tx, versionID, filePath, content)
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit file version: %w", err)
 	}
 
 	return versionID, nil
 }
 
-// GetFileVersionContent gets the content of a file version
+// GetFileVersionContent gets the full content of a file version, whether
+// it's stored as a snapshot blob or reconstructed by replaying diffs; see
+// reconstructVersion.
 func (db *DB) GetFileVersionContent(ctx context.Context, versionID int64) (string, error) {
-	query := `SELECT content FROM file_versions WHERE id = ?`
+	return db.reconstructVersion(ctx, versionID)
+}
 
-	var content string
-	err := db.conn.QueryRowContext(ctx, query, versionID).Scan(&content)
-	if err != nil {
-		return "", err
+// Vacuum prunes historical content_diffs rows older than retention, plus
+// any blobs left unreferenced afterward. A diff is only pruned once the
+// version it produced (new_version_id) is itself a snapshot: that version's
+// content already lives independently in the blob store, so the diff is
+// purely a historical record (as exposed by query-service's diff-streaming
+// API) rather than something GetFileVersionContent still needs. Diffs
+// feeding a diff-only version are kept regardless of age, since pruning one
+// would permanently break replay for that version.
+func (db *DB) Vacuum(ctx context.Context, retention time.Duration) error {
+	cutoff := time.Now().Add(-retention).Unix()
+
+	if _, err := db.conn.ExecContext(ctx, `
+		DELETE FROM content_diffs
+		WHERE timestamp < ?
+		  AND new_version_id IN (SELECT id FROM file_versions WHERE is_snapshot = 1)
+	`, cutoff); err != nil {
+		return fmt.Errorf("failed to prune old diffs: %w", err)
+	}
+
+	if _, err := db.conn.ExecContext(ctx, `
+		DELETE FROM blobs
+		WHERE hash NOT IN (SELECT content_hash FROM file_versions WHERE is_snapshot = 1)
+	`); err != nil {
+		return fmt.Errorf("failed to prune orphan blobs: %w", err)
 	}
 
-	return content, nil
+	return nil
 }
 
 // InsertDiff inserts a diff record
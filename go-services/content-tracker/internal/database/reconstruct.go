@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/obby/content-tracker/internal/diff"
+)
+
+// reconstructVersion returns versionID's full content. A snapshot version
+// (is_snapshot = 1) has its content stored directly in the blob store under
+// its content_hash (or, for a row written before the blob store existed and
+// so having no blob, inline in its content column); a diff-only version is
+// reconstructed by recursively
+// reconstructing the version it diffed against and replaying the stored
+// diff forward. Recursion depth is bounded by DB.snapshotInterval, since
+// every Nth version (at most) is a snapshot.
+//
+// A diff-only version whose content_diffs row hasn't landed yet (it's
+// written by GenerateDiffAsync after the version row itself) can't be
+// reconstructed until it does; callers racing a just-inserted version
+// should expect a transient error in that window, same as they already
+// would racing GetDiffsAfterID for that diff's arrival.
+func (db *DB) reconstructVersion(ctx context.Context, versionID int64) (string, error) {
+	var hash string
+	var isSnapshot bool
+	var inline sql.NullString
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT content_hash, is_snapshot, content FROM file_versions WHERE id = ?
+	`, versionID).Scan(&hash, &isSnapshot, &inline)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up file version %d: %w", versionID, err)
+	}
+	if isSnapshot {
+		content, err := db.getBlob(ctx, hash)
+		if errors.Is(err, sql.ErrNoRows) {
+			return inline.String, nil
+		}
+		return content, err
+	}
+
+	var oldVersionID int64
+	var diffContent string
+	err = db.conn.QueryRowContext(ctx, `
+		SELECT old_version_id, diff_content FROM content_diffs WHERE new_version_id = ?
+	`, versionID).Scan(&oldVersionID, &diffContent)
+	if err != nil {
+		return "", fmt.Errorf("failed to find diff for file version %d (not generated yet?): %w", versionID, err)
+	}
+
+	oldContent, err := db.reconstructVersion(ctx, oldVersionID)
+	if err != nil {
+		return "", err
+	}
+
+	reconstructed, err := diff.ApplyUnifiedDiff(oldContent, diffContent)
+	if err != nil {
+		return "", fmt.Errorf("failed to replay diff onto file version %d: %w", oldVersionID, err)
+	}
+	return reconstructed, nil
+}
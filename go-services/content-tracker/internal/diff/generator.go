@@ -1,11 +1,16 @@
 package diff
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
+// contextLines is the number of unchanged lines kept around each change in
+// a hunk, matching the default used by GNU diff.
+const contextLines = 3
+
 // Generator generates unified diffs
 type Generator struct {
 	dmp *diffmatchpatch.DiffMatchPatch
@@ -24,28 +29,200 @@ func GenerateUnifiedDiff(oldContent, newContent, oldPath, newPath string) (strin
 	return gen.GenerateUnifiedDiff(oldContent, newContent, oldPath, newPath)
 }
 
-// GenerateUnifiedDiff generates a unified diff between old and new content
+// GenerateUnifiedDiff generates a GNU-style unified diff between old and new
+// content, along with accurate added/removed line counts.
+//
+// The diff is computed line-by-line: dmp's line-mode helpers (DiffLinesToChars
+// / DiffCharsToLines) encode whole lines as single "characters" before
+// handing off to DiffMain, so every resulting Diff operand is already a run
+// of complete lines and there's no ambiguity about a DiffEqual run bridging
+// a line boundary mid-line. PatchToText is not used because its patch
+// format isn't the unified diff format DiffRecord.DiffContent's clients
+// expect.
 func (dg *Generator) GenerateUnifiedDiff(oldContent, newContent, oldPath, newPath string) (string, int, int, error) {
-	diffs := dg.dmp.DiffMain(oldContent, newContent, false)
+	oldEncoded, newEncoded, lineArray := dg.dmp.DiffLinesToChars(normalizeTrailingNewline(oldContent), normalizeTrailingNewline(newContent))
+	diffs := dg.dmp.DiffMain(oldEncoded, newEncoded, false)
+	diffs = dg.dmp.DiffCharsToLines(diffs, lineArray)
 
-	// Convert to unified diff format
-	patches := dg.dmp.PatchMake(oldContent, diffs)
-	diffText := dg.dmp.PatchToText(patches)
+	lines := splitIntoLineOps(diffs)
+	linesAdded, linesRemoved := countChangedLines(lines)
+	diffText := buildUnifiedDiff(oldPath, newPath, lines)
 
-	// Calculate lines added/removed
-	linesAdded := 0
-	linesRemoved := 0
+	return diffText, linesAdded, linesRemoved, nil
+}
 
-	oldLines := strings.Split(oldContent, "\n")
-	newLines := strings.Split(newContent, "\n")
+// normalizeTrailingNewline appends a trailing "\n" if s is non-empty and
+// doesn't already end in one. DiffLinesToChars treats a line as a distinct token including
+// whether it carries a trailing "\n", which itself depends only on whether
+// that line happens to be the last line of its text — not on the line's
+// content. Without this, a shared line landing at the end of one side but
+// not the other (e.g. old="a\nb", new="a\nb\nc") is encoded as two different
+// tokens ("b" vs "b\n") and diffs as a spurious delete+insert instead of a
+// no-op. Normalizing both sides the same way before diffing removes that
+// artifact; splitIntoLineOps already strips the trailing phantom line this
+// can introduce at the true end of the text. Empty content is left empty, so
+// it diffs as zero lines rather than as one blank line.
+func normalizeTrailingNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
 
-	// Simple line count (can be improved with proper diff analysis)
-	if len(newLines) > len(oldLines) {
-		linesAdded = len(newLines) - len(oldLines)
-	} else {
-		linesRemoved = len(oldLines) - len(newLines)
+// lineOp is one line of the diff, tagged with the operation that produced it
+// and its position in the old/new file (see splitIntoLineOps for what
+// "position" means for inserted/deleted lines).
+type lineOp struct {
+	kind  diffmatchpatch.Operation
+	text  string
+	oldNo int
+	newNo int
+}
+
+// splitIntoLineOps flattens a line-mode Diff slice into individual lines,
+// dropping the trailing empty element Split produces for a Text that ends
+// in "\n" so a file's final newline doesn't manifest as a spurious blank
+// line.
+func splitIntoLineOps(diffs []diffmatchpatch.Diff) []lineOp {
+	var kinds []diffmatchpatch.Operation
+	var texts []string
+	for _, d := range diffs {
+		segments := strings.Split(d.Text, "\n")
+		if len(segments) > 0 && segments[len(segments)-1] == "" {
+			segments = segments[:len(segments)-1]
+		}
+		for _, s := range segments {
+			kinds = append(kinds, d.Type)
+			texts = append(texts, s)
+		}
 	}
 
-	return diffText, linesAdded, linesRemoved, nil
+	ops := make([]lineOp, len(kinds))
+	oldPos, newPos := 0, 0
+	for i := range kinds {
+		ops[i] = lineOp{kind: kinds[i], text: texts[i], oldNo: oldPos + 1, newNo: newPos + 1}
+		switch kinds[i] {
+		case diffmatchpatch.DiffEqual:
+			oldPos++
+			newPos++
+		case diffmatchpatch.DiffDelete:
+			oldPos++
+		case diffmatchpatch.DiffInsert:
+			newPos++
+		}
+	}
+	return ops
+}
+
+// countChangedLines counts real inserted/deleted lines, unlike the old
+// implementation's len(newLines)-len(oldLines) total-count subtraction,
+// which silently reported zero for one side on any change that both adds
+// and removes lines.
+func countChangedLines(ops []lineOp) (added, removed int) {
+	for _, op := range ops {
+		switch op.kind {
+		case diffmatchpatch.DiffInsert:
+			added++
+		case diffmatchpatch.DiffDelete:
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// hunk is one `@@ -oldStart,oldCount +newStart,newCount @@` block.
+type hunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	lines              []string
+}
+
+// buildUnifiedDiff renders ops as GNU-style unified diff text, returning ""
+// when there are no changes so identical content produces an empty diff.
+func buildUnifiedDiff(oldPath, newPath string, ops []lineOp) string {
+	hunks := buildHunks(ops)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n", oldPath)
+	fmt.Fprintf(&b, "+++ %s\n", newPath)
+	for _, h := range hunks {
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", h.oldStart, h.oldCount, h.newStart, h.newCount)
+		for _, l := range h.lines {
+			b.WriteString(l)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
 }
 
+// buildHunks groups changed lines into hunks, merging two changes into one
+// hunk when they're within 2*contextLines of each other, and pads each hunk
+// with up to contextLines of surrounding unchanged lines.
+func buildHunks(ops []lineOp) []hunk {
+	var changed []int
+	for i, op := range ops {
+		if op.kind != diffmatchpatch.DiffEqual {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var hunks []hunk
+	start, end := changed[0], changed[0]
+	for _, idx := range changed[1:] {
+		if idx-end-1 <= 2*contextLines {
+			end = idx
+			continue
+		}
+		hunks = append(hunks, makeHunk(ops, start, end))
+		start, end = idx, idx
+	}
+	hunks = append(hunks, makeHunk(ops, start, end))
+	return hunks
+}
+
+// makeHunk renders the [start,end] changed range plus contextLines of
+// padding on either side into a hunk.
+func makeHunk(ops []lineOp, start, end int) hunk {
+	lo := start - contextLines
+	if lo < 0 {
+		lo = 0
+	}
+	hi := end + contextLines
+	if hi >= len(ops) {
+		hi = len(ops) - 1
+	}
+
+	h := hunk{oldStart: ops[lo].oldNo, newStart: ops[lo].newNo}
+	for i := lo; i <= hi; i++ {
+		op := ops[i]
+		switch op.kind {
+		case diffmatchpatch.DiffEqual:
+			h.lines = append(h.lines, " "+op.text)
+			h.oldCount++
+			h.newCount++
+		case diffmatchpatch.DiffDelete:
+			h.lines = append(h.lines, "-"+op.text)
+			h.oldCount++
+		case diffmatchpatch.DiffInsert:
+			h.lines = append(h.lines, "+"+op.text)
+			h.newCount++
+		}
+	}
+
+	// GNU convention: a side with zero lines in the hunk points at the line
+	// before the insertion/deletion point rather than the line after it.
+	if h.oldCount == 0 && h.oldStart > 0 {
+		h.oldStart--
+	}
+	if h.newCount == 0 && h.newStart > 0 {
+		h.newStart--
+	}
+
+	return h
+}
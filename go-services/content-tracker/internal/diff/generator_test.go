@@ -0,0 +1,114 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		old, new      string
+		wantAdded     int
+		wantRemoved   int
+		wantContains  []string
+		wantEmptyDiff bool
+	}{
+		{
+			name:        "pure insert",
+			old:         "a\nb\nc\n",
+			new:         "a\nb\nx\nc\n",
+			wantAdded:   1,
+			wantRemoved: 0,
+			wantContains: []string{
+				"--- old.txt",
+				"+++ new.txt",
+				"+x",
+			},
+		},
+		{
+			name:        "pure delete",
+			old:         "a\nb\nc\n",
+			new:         "a\nc\n",
+			wantAdded:   0,
+			wantRemoved: 1,
+			wantContains: []string{
+				"-b",
+			},
+		},
+		{
+			name:        "mixed add and remove",
+			old:         "a\nb\nc\n",
+			new:         "a\nx\nc\nd\n",
+			wantAdded:   2,
+			wantRemoved: 1,
+			wantContains: []string{
+				"-b",
+				"+x",
+				"+d",
+			},
+		},
+		{
+			name:        "no trailing newline on either side",
+			old:         "a\nb",
+			new:         "a\nb\nc",
+			wantAdded:   1,
+			wantRemoved: 0,
+			wantContains: []string{
+				"+c",
+			},
+		},
+		{
+			name:          "identical content produces no diff",
+			old:           "a\nb\nc\n",
+			new:           "a\nb\nc\n",
+			wantAdded:     0,
+			wantRemoved:   0,
+			wantEmptyDiff: true,
+		},
+		{
+			name:        "binary-ish input with no line structure",
+			old:         "\x00\x01\x02binarydata",
+			new:         "\x00\x01\x02binarydata\xff",
+			wantAdded:   1,
+			wantRemoved: 1,
+		},
+	}
+
+	gen := NewDiffGenerator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffText, added, removed, err := gen.GenerateUnifiedDiff(tt.old, tt.new, "old.txt", "new.txt")
+			if err != nil {
+				t.Fatalf("GenerateUnifiedDiff returned error: %v", err)
+			}
+			if added != tt.wantAdded {
+				t.Errorf("linesAdded = %d, want %d", added, tt.wantAdded)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("linesRemoved = %d, want %d", removed, tt.wantRemoved)
+			}
+			if tt.wantEmptyDiff && diffText != "" {
+				t.Errorf("expected empty diff for identical content, got %q", diffText)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(diffText, want) {
+					t.Errorf("diff text missing %q, got:\n%s", want, diffText)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateUnifiedDiffPackageLevelHelper(t *testing.T) {
+	diffText, added, removed, err := GenerateUnifiedDiff("a\n", "a\nb\n", "old.txt", "new.txt")
+	if err != nil {
+		t.Fatalf("GenerateUnifiedDiff returned error: %v", err)
+	}
+	if added != 1 || removed != 0 {
+		t.Errorf("added/removed = %d/%d, want 1/0", added, removed)
+	}
+	if !strings.Contains(diffText, "+b") {
+		t.Errorf("diff text missing inserted line, got:\n%s", diffText)
+	}
+}
@@ -0,0 +1,153 @@
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ApplyUnifiedDiff applies a diff produced by GenerateUnifiedDiff to
+// oldContent and returns the reconstructed new content. It only understands
+// the hunk format buildUnifiedDiff emits (a "--- "/"+++ " header followed by
+// "@@ -oldStart,oldCount +newStart,newCount @@" hunks with ' '/'-'/'+'
+// prefixed lines) and is not a general-purpose patch(1) implementation.
+func ApplyUnifiedDiff(oldContent, diffText string) (string, error) {
+	if diffText == "" {
+		return oldContent, nil
+	}
+
+	hunks, err := parseHunks(diffText)
+	if err != nil {
+		return "", err
+	}
+
+	oldLines := strings.Split(oldContent, "\n")
+	var out []string
+	oldPos := 0 // 0-indexed cursor into oldLines
+
+	for _, h := range hunks {
+		// A pure insertion into empty content is the one case where
+		// GNU-style hunk headers report oldStart = 0 instead of 1 (makeHunk's
+		// oldStart-- normalization turns 1 into 0 when the hunk has no old
+		// lines): treat it the same as "insert before position 0" rather
+		// than going negative.
+		start := h.oldStart - 1
+		if h.oldStart == 0 {
+			start = 0
+		}
+		if start < oldPos || start > len(oldLines) {
+			return "", fmt.Errorf("diff hunk @@ -%d,%d +%d,%d @@ out of range for %d-line content", h.oldStart, h.oldCount, h.newStart, h.newCount, len(oldLines))
+		}
+		out = append(out, oldLines[oldPos:start]...)
+		oldPos = start
+
+		for _, line := range h.lines {
+			if line == "" {
+				continue
+			}
+			switch line[0] {
+			case ' ':
+				if oldPos >= len(oldLines) || oldLines[oldPos] != line[1:] {
+					return "", fmt.Errorf("diff context mismatch at old line %d", oldPos+1)
+				}
+				out = append(out, oldLines[oldPos])
+				oldPos++
+			case '-':
+				if oldPos >= len(oldLines) || oldLines[oldPos] != line[1:] {
+					return "", fmt.Errorf("diff removal mismatch at old line %d", oldPos+1)
+				}
+				oldPos++
+			case '+':
+				out = append(out, line[1:])
+			default:
+				return "", fmt.Errorf("unrecognized diff line %q", line)
+			}
+		}
+	}
+	out = append(out, oldLines[oldPos:]...)
+
+	return strings.Join(out, "\n"), nil
+}
+
+// parsedHunk is one "@@ -oldStart,oldCount +newStart,newCount @@" hunk and
+// its ' '/'-'/'+' prefixed body lines.
+type parsedHunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	lines              []string
+}
+
+// parseHunks extracts the @@ ... @@ hunks from diffText, skipping the
+// leading "--- "/"+++ " header lines.
+func parseHunks(diffText string) ([]parsedHunk, error) {
+	var hunks []parsedHunk
+	var current *parsedHunk
+
+	for _, line := range strings.Split(diffText, "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			continue
+		case strings.HasPrefix(line, "@@ "):
+			if current != nil {
+				hunks = append(hunks, *current)
+			}
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			current = h
+		case line == "":
+			continue
+		default:
+			if current == nil {
+				return nil, fmt.Errorf("diff line %q outside any hunk", line)
+			}
+			current.lines = append(current.lines, line)
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+	return hunks, nil
+}
+
+// parseHunkHeader parses a "@@ -oldStart,oldCount +newStart,newCount @@"
+// line as emitted by buildUnifiedDiff.
+func parseHunkHeader(line string) (*parsedHunk, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("malformed hunk header %q", line)
+	}
+
+	oldStart, oldCount, err := parseHunkRange(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	newStart, newCount, err := parseHunkRange(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+
+	return &parsedHunk{oldStart: oldStart, oldCount: oldCount, newStart: newStart, newCount: newCount}, nil
+}
+
+// parseHunkRange parses a "-12,3" or "+12,3" hunk range into its start line
+// and line count (count defaults to 1 when omitted, same as GNU diff).
+func parseHunkRange(field string) (start, count int, err error) {
+	field = strings.TrimPrefix(field, "-")
+	field = strings.TrimPrefix(field, "+")
+	parts := strings.SplitN(field, ",", 2)
+
+	start, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if len(parts) == 2 {
+		count, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
@@ -0,0 +1,70 @@
+package diff
+
+import "testing"
+
+func TestApplyUnifiedDiffRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+	}{
+		{
+			name: "pure insert",
+			old:  "a\nb\nc\n",
+			new:  "a\nb\nx\nc\n",
+		},
+		{
+			name: "pure delete",
+			old:  "a\nb\nc\n",
+			new:  "a\nc\n",
+		},
+		{
+			name: "mixed add and remove",
+			old:  "a\nb\nc\n",
+			new:  "a\nx\nc\nd\n",
+		},
+		{
+			name: "no trailing newline on either side",
+			old:  "a\nb",
+			new:  "a\nb\nc",
+		},
+		{
+			name: "identical content produces no diff",
+			old:  "a\nb\nc\n",
+			new:  "a\nb\nc\n",
+		},
+		{
+			name: "insert at start of empty file",
+			old:  "",
+			new:  "a\nb\n",
+		},
+	}
+
+	gen := NewDiffGenerator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffText, _, _, err := gen.GenerateUnifiedDiff(tt.old, tt.new, "old.txt", "new.txt")
+			if err != nil {
+				t.Fatalf("GenerateUnifiedDiff returned error: %v", err)
+			}
+
+			got, err := ApplyUnifiedDiff(tt.old, diffText)
+			if err != nil {
+				t.Fatalf("ApplyUnifiedDiff returned error: %v", err)
+			}
+			if got != tt.new {
+				t.Errorf("ApplyUnifiedDiff() = %q, want %q", got, tt.new)
+			}
+		})
+	}
+}
+
+func TestApplyUnifiedDiffRejectsStaleContext(t *testing.T) {
+	diffText, _, _, err := NewDiffGenerator().GenerateUnifiedDiff("a\nb\nc\n", "a\nx\nc\n", "old.txt", "new.txt")
+	if err != nil {
+		t.Fatalf("GenerateUnifiedDiff returned error: %v", err)
+	}
+
+	if _, err := ApplyUnifiedDiff("a\nDIFFERENT\nc\n", diffText); err == nil {
+		t.Error("expected ApplyUnifiedDiff to reject content that doesn't match the diff's context lines")
+	}
+}
@@ -0,0 +1,60 @@
+// Package interceptors provides the cross-cutting unary/stream interceptor
+// chain for this service's gRPC server: structured logging, panic recovery,
+// Prometheus metrics, request timeouts, and bearer-token auth, wired
+// together with grpc-ecosystem/go-grpc-middleware's chaining helpers.
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"google.golang.org/grpc"
+)
+
+// Authenticator validates a bearer token pulled from request metadata. A
+// nil Authenticator in Config disables auth entirely.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) error
+}
+
+// Config configures the interceptor chain.
+type Config struct {
+	// Authenticator, if set, requires every RPC to carry a valid bearer
+	// token in the "authorization" metadata key.
+	Authenticator Authenticator
+	// DefaultTimeout bounds how long a unary RPC may run if the caller
+	// didn't set a deadline of its own; a caller's deadline is kept as is,
+	// even one later than this. Zero disables the default.
+	DefaultTimeout time.Duration
+}
+
+// ServerOptions builds the grpc.ServerOption pair that installs the full
+// interceptor chain on a grpc.Server.
+func ServerOptions(cfg Config) []grpc.ServerOption {
+	unary := []grpc.UnaryServerInterceptor{
+		grpc_prometheus.UnaryServerInterceptor,
+		loggingUnaryInterceptor,
+		recoveryUnaryInterceptor,
+	}
+	stream := []grpc.StreamServerInterceptor{
+		grpc_prometheus.StreamServerInterceptor,
+		loggingStreamInterceptor,
+		recoveryStreamInterceptor,
+	}
+
+	if cfg.DefaultTimeout > 0 {
+		unary = append(unary, timeoutUnaryInterceptor(cfg.DefaultTimeout))
+	}
+
+	if cfg.Authenticator != nil {
+		unary = append(unary, authUnaryInterceptor(cfg.Authenticator))
+		stream = append(stream, authStreamInterceptor(cfg.Authenticator))
+	}
+
+	return []grpc.ServerOption{
+		grpc_middleware.WithUnaryServerChain(unary...),
+		grpc_middleware.WithStreamServerChain(stream...),
+	}
+}
@@ -0,0 +1,50 @@
+package interceptors
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// loggingUnaryInterceptor logs method, duration, peer and error for every
+// unary RPC, replacing the log.Printf lines individual handlers used to
+// open-code.
+func loggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	log.Printf("grpc unary method=%s duration=%s peer=%s error=%v", info.FullMethod, time.Since(start), peerAddr(ctx), err)
+	return resp, err
+}
+
+// loggingStreamInterceptor does the same for streaming RPCs, additionally
+// logging how many messages were sent over the stream.
+func loggingStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	counted := &countingServerStream{ServerStream: ss}
+	err := handler(srv, counted)
+	log.Printf("grpc stream method=%s duration=%s peer=%s messages=%d error=%v",
+		info.FullMethod, time.Since(start), peerAddr(ss.Context()), counted.sent, err)
+	return err
+}
+
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	return p.Addr.String()
+}
+
+// countingServerStream wraps a ServerStream to count streamed messages.
+type countingServerStream struct {
+	grpc.ServerStream
+	sent int
+}
+
+func (s *countingServerStream) SendMsg(m interface{}) error {
+	s.sent++
+	return s.ServerStream.SendMsg(m)
+}
@@ -42,26 +42,64 @@ func (s *ContentTrackerServer) TrackChange(ctx context.Context, req *pb.TrackReq
 	}, nil
 }
 
-// TrackBatch implements the TrackBatch RPC
+// TrackBatch implements the TrackBatch RPC. Files are tracked concurrently
+// via the tracker's transfer manager, so a file already being tracked by a
+// concurrent TrackChange call is deduplicated rather than reprocessed, and a
+// failed attempt is retried with backoff before it's reported as an error.
 func (s *ContentTrackerServer) TrackBatch(req *pb.BatchRequest, stream grpc.ServerStreamingServer[pb.TrackProgress]) error {
-	for _, trackReq := range req.Requests {
-		result, err := s.tracker.TrackChange(stream.Context(), trackReq.FilePath, trackReq.ChangeType, trackReq.ProjectRoot)
-		if err != nil {
-			stream.Send(&pb.TrackProgress{
-				FilePath: trackReq.FilePath,
-				Success:  false,
-				Error:    err.Error(),
-			})
-			continue
+	items := make([]tracker.BatchItem, len(req.Requests))
+	for i, r := range req.Requests {
+		items[i] = tracker.BatchItem{
+			FilePath:    r.FilePath,
+			ChangeType:  r.ChangeType,
+			ProjectRoot: r.ProjectRoot,
 		}
+	}
 
-		stream.Send(&pb.TrackProgress{
-			FilePath:    trackReq.FilePath,
-			Success:     result.Success,
-			Error:       result.Error,
-			ContentHash: result.ContentHash,
-			VersionId:   result.VersionID,
-		})
+	for event := range s.tracker.TrackBatch(stream.Context(), items) {
+		switch {
+		case event.Snapshot != nil:
+			// Aggregate frame, sent on the reporter's own cadence rather
+			// than once per file.
+			if err := stream.Send(&pb.TrackProgress{
+				Batch: &pb.BatchProgress{
+					Total:         int32(event.Snapshot.Total),
+					Completed:     int32(event.Snapshot.Completed),
+					BytesDone:     event.Snapshot.BytesDone,
+					ThroughputBps: event.Snapshot.Throughput,
+					EtaSeconds:    event.Snapshot.ETA.Seconds(),
+				},
+			}); err != nil {
+				return err
+			}
+
+		case event.Result == nil:
+			// Per-file progress frame (read/hashed/stored); only the
+			// terminal result and the aggregate Snapshot are streamed to
+			// callers.
+			continue
+
+		case event.Result.Err != nil:
+			if err := stream.Send(&pb.TrackProgress{
+				FilePath: event.Key,
+				Success:  false,
+				Error:    event.Result.Err.Error(),
+			}); err != nil {
+				return err
+			}
+
+		default:
+			trackResult := event.Result.Value.(*tracker.TrackResult)
+			if err := stream.Send(&pb.TrackProgress{
+				FilePath:    trackResult.FilePath,
+				Success:     trackResult.Success,
+				Error:       trackResult.Error,
+				ContentHash: trackResult.ContentHash,
+				VersionId:   trackResult.VersionID,
+			}); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -8,11 +8,25 @@ import (
 	"io"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/obby/content-tracker/internal/database"
 	"github.com/obby/content-tracker/internal/diff"
+	"github.com/obby/content-tracker/internal/tracker/xfer"
 )
 
+// maxTrackAttempts bounds how many times the transfer manager retries a
+// failing TrackChange before giving up.
+const maxTrackAttempts = 5
+
+// batchParallelism caps how many files a single TrackBatch call tracks
+// concurrently.
+const batchParallelism = 8
+
+// batchReportCadence is how often TrackBatch emits an aggregate
+// xfer.Snapshot event alongside its per-file frames.
+const batchReportCadence = 500 * time.Millisecond
+
 var hashPool = sync.Pool{
 	New: func() interface{} {
 		return sha256.New()
@@ -24,6 +38,8 @@ type ContentTracker struct {
 	db         *database.DB
 	diffGen    *diff.Generator
 	workerPool *WorkerPool
+	xferMgr    *xfer.TransferManager
+	batchMgr   *xfer.BatchManager
 }
 
 // NewContentTracker creates a new content tracker
@@ -33,10 +49,15 @@ func NewContentTracker(dbPath string) (*ContentTracker, error) {
 		return nil, err
 	}
 
+	workerPool := NewWorkerPool(10) // 10 concurrent workers
+	xferMgr := xfer.NewTransferManager(&xferExecutor{pool: workerPool}, maxTrackAttempts)
+
 	return &ContentTracker{
 		db:         db,
 		diffGen:    diff.NewDiffGenerator(),
-		workerPool: NewWorkerPool(10), // 10 concurrent workers
+		workerPool: workerPool,
+		xferMgr:    xferMgr,
+		batchMgr:   xfer.NewBatchManager(xferMgr, batchParallelism, batchReportCadence),
 	}, nil
 }
 
@@ -122,35 +143,58 @@ func (ct *ContentTracker) ReadFile(filePath string) (string, error) {
 	return string(normalized), nil
 }
 
-// TrackChange tracks a file change
+// TrackChange tracks a file change. Concurrent callers for the same
+// filePath+changeType attach to a single in-flight transfer instead of
+// redoing the read/hash/DB work, and a failing transfer is retried with
+// backoff by the transfer manager before the failure is reported. A failure
+// is reported in the returned TrackResult (Success false, Error set); the
+// returned error is always nil.
 func (ct *ContentTracker) TrackChange(ctx context.Context, filePath string, changeType string, projectRoot string) (*TrackResult, error) {
+	w := ct.xferMgr.Transfer(transferKey(filePath, changeType), func(ctx context.Context, progress chan<- xfer.Progress) (interface{}, error) {
+		return ct.trackChangeOnce(ctx, filePath, changeType, projectRoot, progress)
+	})
+	defer w.Release()
+
+	for range w.Updates {
+		// TrackChange is request/response; progress is only consumed by
+		// TrackBatch's streaming path.
+	}
+
+	result := <-w.Done
+	if result.Err != nil {
+		return &TrackResult{Success: false, Error: result.Err.Error()}, nil
+	}
+	return result.Value.(*TrackResult), nil
+}
+
+// transferKey builds the descriptor the transfer manager dedups on.
+func transferKey(filePath, changeType string) string {
+	return filePath + "|" + changeType
+}
+
+// trackChangeOnce does the actual read/hash/diff/store work for a single
+// TrackChange attempt; it's the DoFunc executed by the transfer manager.
+func (ct *ContentTracker) trackChangeOnce(ctx context.Context, filePath, changeType, projectRoot string, progress chan<- xfer.Progress) (*TrackResult, error) {
 	// Read file content
 	content, err := ct.ReadFile(filePath)
 	if err != nil {
-		return &TrackResult{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
+		return nil, err
 	}
+	progress <- xfer.Progress{Message: "read", Current: int64(len(content))}
 
 	// Calculate hash
 	hash, err := ct.CalculateHash(filePath)
 	if err != nil {
-		return &TrackResult{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
+		return nil, err
 	}
 
 	// Get file size
 	info, err := os.Stat(filePath)
 	if err != nil {
-		return &TrackResult{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
+		return nil, err
 	}
 	fileSize := info.Size()
+	progress <- xfer.Progress{Message: "hashed", Current: fileSize, Total: fileSize, BytesDelta: fileSize}
 
 	// Get previous hash from database
 	prevHash, prevVersionID, err := ct.db.GetPreviousHash(ctx, filePath)
@@ -163,6 +207,7 @@ func (ct *ContentTracker) TrackChange(ctx context.Context, filePath string, chan
 	// Check if content changed
 	if prevHash == hash {
 		return &TrackResult{
+			FilePath:    filePath,
 			Success:     true,
 			ContentHash: hash,
 			FileSize:    fileSize,
@@ -173,18 +218,21 @@ func (ct *ContentTracker) TrackChange(ctx context.Context, filePath string, chan
 	// Store new version
 	versionID, err := ct.db.InsertFileVersion(ctx, filePath, hash, content, fileSize)
 	if err != nil {
-		return &TrackResult{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
+		return nil, err
 	}
+	progress <- xfer.Progress{Message: "stored", Current: fileSize, Total: fileSize}
 
-	// Generate diff asynchronously if previous version exists
+	// Generate diff asynchronously if previous version exists. content is
+	// passed directly rather than re-fetched via GetFileVersionContent(versionID):
+	// that version was very likely just stored as diff-only, and its
+	// content_diffs row doesn't exist until this same goroutine inserts it
+	// below, so re-fetching it here would deadlock the version against itself.
 	if prevHash != "" && prevVersionID > 0 {
-		go ct.GenerateDiffAsync(ctx, filePath, prevVersionID, versionID)
+		go ct.GenerateDiffAsync(ctx, filePath, prevVersionID, versionID, content)
 	}
 
 	return &TrackResult{
+		FilePath:    filePath,
 		Success:     true,
 		ContentHash: hash,
 		FileSize:    fileSize,
@@ -192,15 +240,53 @@ func (ct *ContentTracker) TrackChange(ctx context.Context, filePath string, chan
 	}, nil
 }
 
-// GenerateDiffAsync generates diff asynchronously
-func (ct *ContentTracker) GenerateDiffAsync(ctx context.Context, filePath string, oldVersionID, newVersionID int64) {
-	// Get old and new content
-	oldContent, err := ct.db.GetFileVersionContent(ctx, oldVersionID)
-	if err != nil {
-		return
+// BatchItem describes a single file to track as part of a TrackBatch call.
+type BatchItem struct {
+	FilePath    string
+	ChangeType  string
+	ProjectRoot string
+}
+
+// TrackBatch tracks a batch of files concurrently (bounded by
+// batchParallelism) via the shared transfer manager, so a file that's also
+// being tracked by a concurrent TrackChange call is deduplicated rather than
+// processed twice. Events are delivered in completion order, not input
+// order.
+func (ct *ContentTracker) TrackBatch(ctx context.Context, items []BatchItem) <-chan xfer.Event {
+	xferItems := make([]xfer.Item, len(items))
+	for i, item := range items {
+		item := item
+		xferItems[i] = xfer.Item{
+			Key: transferKey(item.FilePath, item.ChangeType),
+			Do: func(ctx context.Context, progress chan<- xfer.Progress) (interface{}, error) {
+				return ct.trackChangeOnce(ctx, item.FilePath, item.ChangeType, item.ProjectRoot, progress)
+			},
+		}
 	}
+	return ct.batchMgr.Run(ctx, xferItems)
+}
+
+// SetSnapshotInterval configures how often tracked versions store a full
+// content snapshot rather than a diff-only version; see
+// database.DB.SetSnapshotInterval.
+func (ct *ContentTracker) SetSnapshotInterval(n int) {
+	ct.db.SetSnapshotInterval(n)
+}
+
+// Vacuum prunes historical diffs older than retention and any blobs left
+// unreferenced afterward; see database.DB.Vacuum.
+func (ct *ContentTracker) Vacuum(ctx context.Context, retention time.Duration) error {
+	return ct.db.Vacuum(ctx, retention)
+}
 
-	newContent, err := ct.db.GetFileVersionContent(ctx, newVersionID)
+// GenerateDiffAsync generates and stores the diff between oldVersionID and
+// newVersionID. newContent is the already-read content of newVersionID,
+// supplied by the caller rather than re-fetched: newVersionID was very
+// likely just stored as diff-only, and GetFileVersionContent(newVersionID)
+// reconstructs it by reading the very content_diffs row this call is
+// responsible for inserting, so fetching it here would never succeed.
+func (ct *ContentTracker) GenerateDiffAsync(ctx context.Context, filePath string, oldVersionID, newVersionID int64, newContent string) {
+	oldContent, err := ct.db.GetFileVersionContent(ctx, oldVersionID)
 	if err != nil {
 		return
 	}
@@ -219,6 +305,7 @@ func (ct *ContentTracker) GenerateDiffAsync(ctx context.Context, filePath string
 
 // TrackResult represents the result of tracking a file change
 type TrackResult struct {
+	FilePath    string
 	Success     bool
 	Error       string
 	ContentHash string
@@ -70,3 +70,24 @@ func (wp *WorkerPool) worker() {
 	}
 }
 
+// execFunc adapts a plain function to the Task interface.
+type execFunc func(ctx context.Context) error
+
+func (f execFunc) Execute(ctx context.Context) error {
+	return f(ctx)
+}
+
+// xferExecutor adapts a WorkerPool to xfer.Executor so the transfer manager
+// runs transfers on the same bounded pool as everything else.
+type xferExecutor struct {
+	pool *WorkerPool
+}
+
+// Submit runs fn on the worker pool, reporting whether it was accepted.
+func (e *xferExecutor) Submit(fn func(ctx context.Context)) bool {
+	return e.pool.Submit(execFunc(func(ctx context.Context) error {
+		fn(ctx)
+		return nil
+	}))
+}
+
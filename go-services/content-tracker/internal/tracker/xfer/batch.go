@@ -0,0 +1,123 @@
+package xfer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Item describes one unit of batch work keyed for dedup/retry by the
+// underlying TransferManager.
+type Item struct {
+	Key string
+	Do  DoFunc
+}
+
+// Event is emitted on a BatchManager's output channel: a per-item progress
+// update, a per-item terminal result (exactly once, last, per Key), or a
+// batch-wide Snapshot emitted by the reporter on its own cadence.
+type Event struct {
+	Key      string
+	Progress *Progress
+	Result   *Result
+	Snapshot *Snapshot
+}
+
+// BatchManager layers concurrent, bounded-parallelism scheduling on top of
+// a TransferManager, mirroring Docker's UploadManager/DownloadManager
+// wrapping the shared transfer manager for batched operations.
+type BatchManager struct {
+	tm            *TransferManager
+	maxParallel   int
+	reportCadence time.Duration
+}
+
+// NewBatchManager creates a BatchManager that runs at most maxParallel
+// items of a batch concurrently and reports aggregate progress every
+// reportCadence (or every defaultReportCadence, if reportCadence <= 0).
+func NewBatchManager(tm *TransferManager, maxParallel int, reportCadence time.Duration) *BatchManager {
+	if maxParallel <= 0 {
+		maxParallel = 4
+	}
+	if reportCadence <= 0 {
+		reportCadence = defaultReportCadence
+	}
+	return &BatchManager{tm: tm, maxParallel: maxParallel, reportCadence: reportCadence}
+}
+
+// Run schedules items with at most maxParallel in flight at a time. Each
+// item's progress and terminal result are delivered on the returned
+// channel, interleaved with batch-wide Snapshot events emitted every
+// reportCadence by a Reporter fed from the same per-item updates. The
+// channel is closed once every item has finished and a final snapshot has
+// been sent.
+func (b *BatchManager) Run(ctx context.Context, items []Item) <-chan Event {
+	out := make(chan Event, b.maxParallel*2)
+	sem := make(chan struct{}, b.maxParallel)
+	reporter := NewReporter(len(items))
+
+	// send delivers e on out, but gives up once ctx is done instead of
+	// blocking forever. A caller that stops draining out early (e.g.
+	// TrackBatch's stream.Send failing mid-batch) would otherwise leak every
+	// goroutine still trying to send on it.
+	send := func(e Event) {
+		select {
+		case out <- e:
+		case <-ctx.Done():
+		}
+	}
+
+	itemsDone := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, item := range items {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				reporter.ItemDone()
+				send(Event{Key: item.Key, Result: &Result{Err: ctx.Err()}})
+				continue
+			}
+
+			wg.Add(1)
+			go func(item Item) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				w := b.tm.Transfer(item.Key, item.Do)
+				for p := range w.Updates {
+					p := p
+					reporter.Observe(p.BytesDelta)
+					send(Event{Key: item.Key, Progress: &p})
+				}
+				r := <-w.Done
+				w.Release()
+				reporter.ItemDone()
+				send(Event{Key: item.Key, Result: &r})
+			}(item)
+		}
+		wg.Wait()
+		close(itemsDone)
+	}()
+
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(b.reportCadence)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-itemsDone:
+				snap := reporter.Snapshot()
+				send(Event{Snapshot: &snap})
+				return
+			case <-ticker.C:
+				snap := reporter.Snapshot()
+				send(Event{Snapshot: &snap})
+			}
+		}
+	}()
+
+	return out
+}
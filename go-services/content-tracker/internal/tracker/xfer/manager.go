@@ -0,0 +1,123 @@
+package xfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSubmitRejected is the result Err when the executor rejects a transfer
+// (e.g. its WorkerPool is shutting down), so watchers still get a terminal
+// Result instead of blocking on Done forever.
+var ErrSubmitRejected = errors.New("xfer: executor rejected transfer")
+
+// Executor runs work submitted by the TransferManager. It is implemented by
+// the tracker's WorkerPool so transfers execute on the same bounded
+// goroutine pool as everything else instead of spawning unbounded
+// goroutines.
+type Executor interface {
+	Submit(fn func(ctx context.Context)) bool
+}
+
+// TransferManager keys in-flight operations by a descriptor so concurrent
+// callers attach as watchers to the same underlying transfer instead of
+// re-executing it, and automatically retries a failed transfer with
+// exponential backoff before giving up.
+type TransferManager struct {
+	mu        sync.Mutex
+	transfers map[string]*transfer
+
+	executor    Executor
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewTransferManager creates a manager that executes transfers via
+// executor, retrying a failing DoFunc up to maxAttempts times with backoff
+// starting at 100ms and capped at 5s.
+func NewTransferManager(executor Executor, maxAttempts int) *TransferManager {
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	return &TransferManager{
+		transfers:   make(map[string]*transfer),
+		executor:    executor,
+		maxAttempts: maxAttempts,
+		minBackoff:  100 * time.Millisecond,
+		maxBackoff:  5 * time.Second,
+	}
+}
+
+// Transfer starts (or attaches to) the transfer identified by key. A key
+// that is already in flight returns a watcher on the existing transfer
+// without invoking do again.
+func (m *TransferManager) Transfer(key string, do DoFunc) *Watcher {
+	m.mu.Lock()
+	t, exists := m.transfers[key]
+	if !exists {
+		t = newTransfer(context.Background())
+		m.transfers[key] = t
+	}
+	m.mu.Unlock()
+
+	w := t.watch()
+
+	if !exists {
+		submitted := m.executor.Submit(func(_ context.Context) {
+			m.run(key, t, do)
+		})
+		if !submitted {
+			// run (and its deferred cleanup) never starts, so do it here:
+			// otherwise key stays in m.transfers forever and every watcher's
+			// Done blocks forever waiting on a result that will never come.
+			m.mu.Lock()
+			delete(m.transfers, key)
+			m.mu.Unlock()
+			t.finish(Result{Err: ErrSubmitRejected})
+		}
+	}
+
+	return w
+}
+
+// run executes do against t, retrying with exponential backoff until it
+// succeeds, the attempt budget is exhausted, or every watcher has released
+// (cancelling t.ctx, at which point retrying would be pointless).
+func (m *TransferManager) run(key string, t *transfer, do DoFunc) {
+	defer func() {
+		m.mu.Lock()
+		delete(m.transfers, key)
+		m.mu.Unlock()
+	}()
+
+	backoff := m.minBackoff
+	var result Result
+
+	for attempt := 1; attempt <= m.maxAttempts; attempt++ {
+		value, err := do(t.ctx, t.progressIn)
+		result = Result{Value: value, Err: err}
+		if err == nil {
+			break
+		}
+		if t.ctx.Err() != nil || attempt == m.maxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-t.ctx.Done():
+		}
+		if t.ctx.Err() != nil {
+			break
+		}
+
+		backoff *= 2
+		if backoff > m.maxBackoff {
+			backoff = m.maxBackoff
+		}
+	}
+
+	t.finish(result)
+}
@@ -0,0 +1,121 @@
+package xfer
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultReportCadence is how often a Reporter's aggregate state is
+// snapshotted for streaming consumers when the caller doesn't configure its
+// own cadence.
+const defaultReportCadence = 500 * time.Millisecond
+
+// throughputAlpha is the EMA smoothing factor applied to each sample of
+// instantaneous bytes/sec; closer to 1 reacts faster to bursts, closer to 0
+// smooths over noisy per-file timings.
+const throughputAlpha = 0.3
+
+// Snapshot is a point-in-time aggregate over a batch of transfers.
+type Snapshot struct {
+	Total      int
+	Completed  int
+	BytesDone  int64
+	Throughput float64       // bytes/sec, EMA-smoothed
+	ETA        time.Duration // estimated time remaining
+}
+
+// Reporter aggregates per-item progress into periodic Snapshots, the way
+// cheggaaa/pb's render loop works off a ticker rather than one frame per
+// update. It only deals in counts and byte deltas, not how the underlying
+// batch is executed or consumed, so the same Reporter aggregates a gRPC
+// TrackBatch stream or a non-RPC batch such as a startup directory scan.
+type Reporter interface {
+	// Observe folds bytes processed toward the batch total into the
+	// running throughput estimate.
+	Observe(bytesDelta int64)
+	// ItemDone marks one of Total items finished, successfully or not.
+	ItemDone()
+	// Snapshot returns the current aggregate state.
+	Snapshot() Snapshot
+}
+
+// batchReporter is the default Reporter implementation.
+type batchReporter struct {
+	mu        sync.Mutex
+	total     int
+	completed int
+	bytesDone int64
+
+	throughput  float64
+	lastSample  time.Time
+	bytesAtLast int64
+}
+
+// NewReporter creates a Reporter for a batch of total items.
+func NewReporter(total int) Reporter {
+	return &batchReporter{
+		total:      total,
+		lastSample: time.Now(),
+	}
+}
+
+// Observe implements Reporter.
+func (r *batchReporter) Observe(bytesDelta int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bytesDone += bytesDelta
+	r.sample()
+}
+
+// ItemDone implements Reporter.
+func (r *batchReporter) ItemDone() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.completed++
+}
+
+// sample folds the bytes processed since the previous sample into the EMA
+// throughput estimate. Called with mu held.
+func (r *batchReporter) sample() {
+	now := time.Now()
+	elapsed := now.Sub(r.lastSample).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	instant := float64(r.bytesDone-r.bytesAtLast) / elapsed
+	if r.throughput == 0 {
+		r.throughput = instant
+	} else {
+		r.throughput = throughputAlpha*instant + (1-throughputAlpha)*r.throughput
+	}
+	r.lastSample = now
+	r.bytesAtLast = r.bytesDone
+}
+
+// Snapshot implements Reporter. ETA is derived from the average bytes seen
+// per completed item projected over the items still outstanding, divided by
+// the current throughput estimate.
+func (r *batchReporter) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var eta time.Duration
+	if r.total > 0 && r.completed < r.total && r.throughput > 0 {
+		doneForAvg := r.completed
+		if doneForAvg == 0 {
+			doneForAvg = 1
+		}
+		avgBytesPerItem := float64(r.bytesDone) / float64(doneForAvg)
+		remaining := float64(r.total-r.completed) * avgBytesPerItem
+		eta = time.Duration(remaining / r.throughput * float64(time.Second))
+	}
+
+	return Snapshot{
+		Total:      r.total,
+		Completed:  r.completed,
+		BytesDone:  r.bytesDone,
+		Throughput: r.throughput,
+		ETA:        eta,
+	}
+}
@@ -0,0 +1,167 @@
+// Package xfer implements a transfer manager modeled on Docker's image
+// transfer manager: callers racing in with the same descriptor attach as
+// watchers to a single in-flight operation instead of re-executing it.
+package xfer
+
+import (
+	"context"
+	"sync"
+)
+
+// Progress represents a single update emitted while a transfer is in flight.
+type Progress struct {
+	Current int64
+	Total   int64
+	Message string
+
+	// BytesDelta is the number of bytes this update contributes to the
+	// batch's overall byte count, separate from Current/Total which
+	// describe this one operation's own progress. DoFuncs that report
+	// several sub-steps (read, hash, store) set it on only one of them so a
+	// Reporter summing BytesDelta across a batch doesn't triple-count a
+	// file's size.
+	BytesDelta int64
+}
+
+// Result is the terminal outcome of a transfer.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// DoFunc performs the actual work for a transfer. It may send on
+// progressChan only until it returns; the manager closes the channel once
+// the transfer has finished, after its last attempt.
+type DoFunc func(ctx context.Context, progressChan chan<- Progress) (interface{}, error)
+
+// Watcher is handed to every caller attached to a transfer. Updates carries
+// progress events; Done carries the single terminal Result.
+type Watcher struct {
+	Updates <-chan Progress
+	Done    <-chan Result
+
+	release func()
+}
+
+// Release detaches this watcher from its transfer. Once every watcher
+// attached to a transfer has released, the transfer's context is cancelled.
+func (w *Watcher) Release() {
+	w.release()
+}
+
+// transfer tracks a single in-flight unit of work shared by any number of
+// watchers.
+type transfer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	progressIn chan Progress
+
+	mu       sync.Mutex
+	watchers map[int]chan Progress
+	nextID   int
+	refCount int
+	result   *Result
+
+	done chan struct{}
+}
+
+func newTransfer(parent context.Context) *transfer {
+	ctx, cancel := context.WithCancel(parent)
+	t := &transfer{
+		ctx:        ctx,
+		cancel:     cancel,
+		progressIn: make(chan Progress, 100),
+		watchers:   make(map[int]chan Progress),
+		done:       make(chan struct{}),
+	}
+	go t.broadcastLoop()
+	return t
+}
+
+// broadcastLoop fans progress events out to every currently attached
+// watcher. Slow watchers don't block the transfer: a full channel just
+// drops the update, since Done always carries the final result regardless.
+func (t *transfer) broadcastLoop() {
+	for p := range t.progressIn {
+		t.mu.Lock()
+		for _, ch := range t.watchers {
+			select {
+			case ch <- p:
+			default:
+			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// watch registers a new watcher. If the transfer has already finished, the
+// result is delivered on Done immediately.
+func (t *transfer) watch() *Watcher {
+	t.mu.Lock()
+	id := t.nextID
+	t.nextID++
+	t.refCount++
+	updates := make(chan Progress, 100)
+	if t.watchers != nil {
+		t.watchers[id] = updates
+	}
+	result := t.result
+	t.mu.Unlock()
+
+	doneCh := make(chan Result, 1)
+	if result != nil {
+		doneCh <- *result
+	} else {
+		go func() {
+			<-t.done
+			t.mu.Lock()
+			r := t.result
+			t.mu.Unlock()
+			if r != nil {
+				doneCh <- *r
+			}
+		}()
+	}
+
+	return &Watcher{
+		Updates: updates,
+		Done:    doneCh,
+		release: func() { t.release(id) },
+	}
+}
+
+// release drops a single watcher. The transfer is only cancelled once
+// refCount reaches zero, i.e. once every attached watcher has released.
+func (t *transfer) release(id int) {
+	t.mu.Lock()
+	if t.watchers != nil {
+		if ch, ok := t.watchers[id]; ok {
+			delete(t.watchers, id)
+			close(ch)
+		}
+	}
+	t.refCount--
+	remaining := t.refCount
+	t.mu.Unlock()
+
+	if remaining <= 0 {
+		t.cancel()
+	}
+}
+
+// finish records the terminal result, closes every watcher's update channel
+// and wakes anyone blocked waiting on Done.
+func (t *transfer) finish(result Result) {
+	t.mu.Lock()
+	t.result = &result
+	watchers := t.watchers
+	t.watchers = nil
+	t.mu.Unlock()
+
+	close(t.progressIn)
+	for _, ch := range watchers {
+		close(ch)
+	}
+	close(t.done)
+}
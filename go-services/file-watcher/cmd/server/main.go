@@ -1,22 +1,57 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/obby/file-watcher/config"
 	"github.com/obby/file-watcher/internal/patterns"
 	"github.com/obby/file-watcher/internal/server"
+	"github.com/obby/file-watcher/internal/supervisor"
 	"github.com/obby/file-watcher/internal/watcher"
 	pb "github.com/obby/file-watcher/proto/generated"
 	"google.golang.org/grpc"
 )
 
+// grpcService adapts a *grpc.Server to supervisor.Service: Serve blocks
+// until ctx is canceled or the server fails on its own, gracefully
+// stopping the server in the former case.
+type grpcService struct {
+	server *grpc.Server
+	lis    net.Listener
+}
+
+func (g *grpcService) Serve(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() { errCh <- g.server.Serve(g.lis) }()
+
+	select {
+	case <-ctx.Done():
+		g.server.GracefulStop()
+		<-errCh
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
+
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	if lvl, err := log.ParseLevel(cfg.LogLevel); err == nil {
+		log.SetLevel(lvl)
+	} else {
+		log.Warnf("Unrecognized LOG_LEVEL %q, defaulting to info", cfg.LogLevel)
+	}
+
 	// Create pattern matcher
 	matcher := patterns.NewMatcher()
 
@@ -25,12 +60,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create file watcher: %v", err)
 	}
-	defer fw.Stop()
-
-	// Start watcher
-	if err := fw.Start(); err != nil {
-		log.Fatalf("Failed to start file watcher: %v", err)
-	}
+	fw.SetMaxWatchedDirs(cfg.MaxWatchedDirs)
+	fw.SetPollInterval(time.Duration(cfg.PollIntervalMs) * time.Millisecond)
+	fw.SetForcePoll(cfg.ForcePoll)
 
 	// Create gRPC server
 	grpcServer := grpc.NewServer()
@@ -39,17 +71,34 @@ func main() {
 	fileWatcherServer := server.NewFileWatcherServer(fw)
 	pb.RegisterFileWatcherServer(grpcServer, fileWatcherServer)
 
-	// Start listening
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Port))
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
+	sup := supervisor.New()
+	sup.Add("watcher", fw)
+	sup.Add("grpc", &grpcService{server: grpcServer, lis: lis})
+	sup.Add("metrics", &server.MetricsService{Addr: fmt.Sprintf(":%d", cfg.MetricsPort)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := sup.Serve(ctx); err != nil {
+			log.Printf("supervisor exited: %v", err)
+		}
+	}()
+
 	log.Printf("File Watcher Service listening on :%d", cfg.Port)
 
-	// Serve
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
-	}
-}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
 
+	log.Printf("Shutting down File Watcher Service...")
+	cancel()
+	<-done
+}
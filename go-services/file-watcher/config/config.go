@@ -7,9 +7,13 @@ import (
 
 // Config holds the configuration for the file watcher service
 type Config struct {
-	Port       int
-	LogLevel   string
-	DebounceMs int
+	Port           int
+	LogLevel       string
+	DebounceMs     int
+	MetricsPort    int
+	MaxWatchedDirs int
+	PollIntervalMs int
+	ForcePoll      bool
 }
 
 // LoadConfig loads configuration from environment variables with defaults
@@ -33,10 +37,45 @@ func LoadConfig() *Config {
 		}
 	}
 
+	metricsPort := 9090
+	if mpStr := os.Getenv("METRICS_PORT"); mpStr != "" {
+		if mp, err := strconv.Atoi(mpStr); err == nil {
+			metricsPort = mp
+		}
+	}
+
+	maxWatchedDirs := 50000
+	if mwdStr := os.Getenv("MAX_WATCHED_DIRS"); mwdStr != "" {
+		if mwd, err := strconv.Atoi(mwdStr); err == nil {
+			maxWatchedDirs = mwd
+		}
+	}
+
+	pollIntervalMs := 2000
+	if piStr := os.Getenv("POLL_INTERVAL_MS"); piStr != "" {
+		if pi, err := strconv.Atoi(piStr); err == nil {
+			pollIntervalMs = pi
+		}
+	}
+
+	// WATCHER_FORCE_POLL forces every watched path onto the polling
+	// fallback regardless of detectWSL/isDrvFsPath, e.g. for network
+	// mounts that have the same unreliable-inotify problem DrvFS does.
+	forcePoll := false
+	if fp := os.Getenv("WATCHER_FORCE_POLL"); fp != "" {
+		if b, err := strconv.ParseBool(fp); err == nil {
+			forcePoll = b
+		}
+	}
+
 	return &Config{
-		Port:       port,
-		LogLevel:   logLevel,
-		DebounceMs: debounceMs,
+		Port:           port,
+		LogLevel:       logLevel,
+		DebounceMs:     debounceMs,
+		MetricsPort:    metricsPort,
+		MaxWatchedDirs: maxWatchedDirs,
+		PollIntervalMs: pollIntervalMs,
+		ForcePoll:      forcePoll,
 	}
 }
 
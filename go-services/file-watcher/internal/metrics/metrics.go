@@ -0,0 +1,33 @@
+// Package metrics defines the Prometheus metrics the file watcher exposes
+// on its /metrics endpoint (see server.MetricsService), for operational
+// tuning of debounce delay and channel buffer sizes.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// EventsTotal counts filesystem events handed off to Events(), by
+	// event type (created, modified, deleted, renamed).
+	EventsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "obby_watcher_events_total",
+		Help: "Filesystem events forwarded to Events(), by event type.",
+	}, []string{"event_type"})
+
+	// EventsDroppedTotal counts events discarded because a downstream
+	// channel (the debounce-to-pending handoff or the pending-to-events
+	// handoff) was full.
+	EventsDroppedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "obby_watcher_events_dropped_total",
+		Help: "Events dropped because a downstream channel was full.",
+	})
+
+	// DebouncePending tracks how many paths currently have a pending
+	// debounce timer running.
+	DebouncePending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "obby_watcher_debounce_pending",
+		Help: "Paths currently waiting out their debounce window.",
+	})
+)
@@ -1,6 +1,8 @@
 package patterns
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -8,126 +10,271 @@ import (
 	"github.com/gobwas/glob"
 )
 
-// Matcher handles pattern matching for watch and ignore patterns
-type Matcher struct {
-	watchPatterns  []glob.Glob
-	ignorePatterns []glob.Glob
-	mu             sync.RWMutex
+// pathSeparator is the separator gitignore-style globs are compiled
+// against: a bare "*" stops at a path segment boundary, while "**" still
+// matches across them (gobwas/glob treats "**" specially regardless of the
+// configured separator).
+const pathSeparator = '/'
+
+// Rule is one parsed, non-comment, non-blank line of a watch/ignore
+// pattern file, keeping the gitignore semantics a raw glob string can't
+// express on its own.
+type Rule struct {
+	// Raw is the original pattern line, unmodified, for display/debugging.
+	Raw string
+	// Source identifies which pattern set this rule came from: "watch" or
+	// "ignore".
+	Source string
+	// Negate is true for a "!pattern" line: a later match re-includes a
+	// path an earlier rule excluded, instead of excluding it.
+	Negate bool
+	// Anchored is true for a pattern with a leading "/": it only matches
+	// relative to the root of the watched tree, not at any depth.
+	Anchored bool
+	// DirOnly is true for a pattern with a trailing "/": it can only match
+	// directories, never plain files.
+	DirOnly bool
+
+	glob glob.Glob
 }
 
-// NewMatcher creates a new pattern matcher
-func NewMatcher() *Matcher {
-	return &Matcher{
-		watchPatterns:  make([]glob.Glob, 0),
-		ignorePatterns: make([]glob.Glob, 0),
+// String renders r the way it would appear in a rule trail, e.g.
+// "!build/ (ignore)".
+func (r Rule) String() string {
+	return fmt.Sprintf("%s (%s)", r.Raw, r.Source)
+}
+
+// matches reports whether relPath (forward-slashed, relative to the
+// matcher's root) matches r, given whether relPath is a directory.
+func (r Rule) matches(relPath string, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
 	}
+
+	if r.Anchored {
+		return r.glob.Match(relPath)
+	}
+
+	// An unanchored pattern matches at any depth: try the full relative
+	// path first, then every suffix starting at a segment boundary (which
+	// also covers matching against the bare basename).
+	segments := strings.Split(relPath, "/")
+	for i := range segments {
+		if r.glob.Match(strings.Join(segments[i:], "/")) {
+			return true
+		}
+	}
+	return false
 }
 
-// SetWatchPatterns sets the watch patterns
-func (m *Matcher) SetWatchPatterns(patterns []string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// parseRule parses one pattern line into a Rule. Leading/trailing
+// whitespace must already be stripped and blank/comment lines filtered out
+// by the caller.
+func parseRule(line, source string) (Rule, error) {
+	raw := line
+	line = filepath.ToSlash(line)
+
+	negate := false
+	switch {
+	case strings.HasPrefix(line, "!"):
+		negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		// A backslash escapes gitignore's leading "!"/"#" so the pattern
+		// can start with a literal one.
+		line = line[1:]
+	}
+
+	anchored := strings.HasPrefix(line, "/")
+	if anchored {
+		line = line[1:]
+	}
 
-	m.watchPatterns = make([]glob.Glob, 0, len(patterns))
+	dirOnly := strings.HasSuffix(line, "/")
+	if dirOnly {
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	if line == "" {
+		return Rule{}, fmt.Errorf("pattern %q has no glob left after stripping anchors", raw)
+	}
+
+	g, err := glob.Compile(line, pathSeparator)
+	if err != nil {
+		return Rule{}, fmt.Errorf("pattern %q: %w", raw, err)
+	}
+
+	return Rule{
+		Raw:      raw,
+		Source:   source,
+		Negate:   negate,
+		Anchored: anchored,
+		DirOnly:  dirOnly,
+		glob:     g,
+	}, nil
+}
+
+// parseRules parses patterns (one per line, blank lines and "#" comments
+// skipped) into Rules tagged with source, preserving file order — order
+// matters, since a later "!pattern" can re-include what an earlier rule
+// excluded.
+func parseRules(patterns []string, source string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(patterns))
 	for _, pattern := range patterns {
 		pattern = strings.TrimSpace(pattern)
 		if pattern == "" || strings.HasPrefix(pattern, "#") {
 			continue
 		}
 
-		// Normalize pattern: use forward slashes
-		pattern = filepath.ToSlash(pattern)
-
-		g, err := glob.Compile(pattern)
+		rule, err := parseRule(pattern, source)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		m.watchPatterns = append(m.watchPatterns, g)
+		rules = append(rules, rule)
 	}
+	return rules, nil
+}
 
-	return nil
+// evaluate runs every rule against relPath in order, git-style: the last
+// matching rule decides the outcome, so a later "!pattern" overturns an
+// earlier exclusion. It returns the outcome and the trail of every rule
+// that matched along the way, for Explain.
+func evaluate(rules []Rule, relPath string, isDir bool) (matched bool, trail []Rule) {
+	for _, rule := range rules {
+		if !rule.matches(relPath, isDir) {
+			continue
+		}
+		trail = append(trail, rule)
+		matched = !rule.Negate
+	}
+	return matched, trail
 }
 
-// SetIgnorePatterns sets the ignore patterns
-func (m *Matcher) SetIgnorePatterns(patterns []string) error {
+// Matcher handles gitignore-compatible pattern matching for watch and
+// ignore patterns.
+type Matcher struct {
+	watchPatterns  []Rule
+	ignorePatterns []Rule
+	root           string
+	mu             sync.RWMutex
+}
+
+// NewMatcher creates a new pattern matcher
+func NewMatcher() *Matcher {
+	return &Matcher{
+		watchPatterns:  make([]Rule, 0),
+		ignorePatterns: make([]Rule, 0),
+	}
+}
+
+// SetRoot sets the root of the watched tree that anchored patterns
+// (leading "/") are resolved against, updating it on every call. A
+// FileWatcher sharing one Matcher across several AddPath roots will
+// therefore have anchored patterns resolve relative to whichever root was
+// set most recently; callers watching multiple independent roots with
+// anchored patterns need a Matcher per root to get correct anchoring for
+// each.
+func (m *Matcher) SetRoot(root string) {
+	if root == "" {
+		return
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.root = filepath.ToSlash(root)
+}
 
-	m.ignorePatterns = make([]glob.Glob, 0, len(patterns))
-	for _, pattern := range patterns {
-		pattern = strings.TrimSpace(pattern)
-		if pattern == "" || strings.HasPrefix(pattern, "#") {
-			continue
-		}
+// relPathLocked returns path relative to the matcher's root (forward
+// slashed), or path itself, normalized, if no root has been set or path
+// isn't under it. Called with mu held for reading.
+func (m *Matcher) relPathLocked(path string) string {
+	normalized := filepath.ToSlash(path)
+	if m.root == "" {
+		return normalized
+	}
 
-		// Normalize pattern: use forward slashes
-		pattern = filepath.ToSlash(pattern)
+	rel, err := filepath.Rel(filepath.FromSlash(m.root), filepath.FromSlash(normalized))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return normalized
+	}
+	return filepath.ToSlash(rel)
+}
 
-		g, err := glob.Compile(pattern)
-		if err != nil {
-			return err
-		}
-		m.ignorePatterns = append(m.ignorePatterns, g)
+// SetWatchPatterns sets the watch patterns
+func (m *Matcher) SetWatchPatterns(patterns []string) error {
+	rules, err := parseRules(patterns, "watch")
+	if err != nil {
+		return err
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchPatterns = rules
 	return nil
 }
 
-// IsIgnored checks if a path matches any ignore pattern
-func (m *Matcher) IsIgnored(path string) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// SetIgnorePatterns sets the ignore patterns
+func (m *Matcher) SetIgnorePatterns(patterns []string) error {
+	rules, err := parseRules(patterns, "ignore")
+	if err != nil {
+		return err
+	}
 
-	// Normalize path: use forward slashes
-	normalizedPath := filepath.ToSlash(path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ignorePatterns = rules
+	return nil
+}
 
-	// Check against ignore patterns
-	for _, pattern := range m.ignorePatterns {
-		if pattern.Match(normalizedPath) {
-			return true
-		}
-		// Also check just the filename
-		if pattern.Match(filepath.Base(normalizedPath)) {
-			return true
-		}
-	}
+// IsIgnored reports whether path matches the ignore patterns: rules are
+// evaluated in order and the last match decides, so a later "!pattern" can
+// re-include a path an earlier rule excluded.
+func (m *Matcher) IsIgnored(path string, isDir bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	return false
+	relPath := m.relPathLocked(path)
+	ignored, _ := evaluate(m.ignorePatterns, relPath, isDir)
+	return ignored
 }
 
-// IsWatched checks if a path matches any watch pattern
-// Returns false if no watch patterns are defined (STRICT MODE)
-func (m *Matcher) IsWatched(path string) bool {
+// IsWatched reports whether path matches the watch patterns. Returns false
+// if no watch patterns are defined (STRICT MODE: watch nothing by
+// default). Rules are evaluated in order and the last match decides.
+func (m *Matcher) IsWatched(path string, isDir bool) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	// STRICT MODE: If no patterns specified, watch NOTHING
 	if len(m.watchPatterns) == 0 {
 		return false
 	}
 
-	// Normalize path: use forward slashes
-	normalizedPath := filepath.ToSlash(path)
+	relPath := m.relPathLocked(path)
+	watched, _ := evaluate(m.watchPatterns, relPath, isDir)
+	return watched
+}
 
-	// Check against watch patterns
-	for _, pattern := range m.watchPatterns {
-		if pattern.Match(normalizedPath) {
-			return true
-		}
-		// Also check just the filename
-		if pattern.Match(filepath.Base(normalizedPath)) {
-			return true
-		}
-		// Check if path is inside a watched directory (for directory patterns ending with /)
-		pathParts := strings.Split(normalizedPath, "/")
-		for i := range pathParts {
-			partialPath := strings.Join(pathParts[:i+1], "/") + "/"
-			if pattern.Match(partialPath) {
-				return true
-			}
-		}
+// Explain returns every watch and ignore rule that matched path, in
+// evaluation order, so callers can see why a path is or isn't being
+// watched: the last Rule with Source "watch" and the last with Source
+// "ignore" are the ones that actually decided the outcome. isDir is
+// determined with an os.Stat of path; if that fails (e.g. the path was
+// just deleted), path is treated as a plain file.
+func (m *Matcher) Explain(path string) []Rule {
+	isDir := false
+	if info, err := os.Stat(path); err == nil {
+		isDir = info.IsDir()
 	}
 
-	return false
-}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	relPath := m.relPathLocked(path)
 
+	var trail []Rule
+	_, watchTrail := evaluate(m.watchPatterns, relPath, isDir)
+	_, ignoreTrail := evaluate(m.ignorePatterns, relPath, isDir)
+	trail = append(trail, watchTrail...)
+	trail = append(trail, ignoreTrail...)
+	return trail
+}
@@ -0,0 +1,28 @@
+package patterns
+
+import "testing"
+
+func TestSetRootUpdatesOnEveryCall(t *testing.T) {
+	m := NewMatcher()
+
+	m.SetRoot("/repo/one")
+	if got := m.relPathLocked("/repo/one/src/main.go"); got != "src/main.go" {
+		t.Fatalf("relPathLocked after first SetRoot = %q, want %q", got, "src/main.go")
+	}
+
+	m.SetRoot("/repo/two")
+	if got := m.relPathLocked("/repo/two/src/main.go"); got != "src/main.go" {
+		t.Fatalf("relPathLocked after second SetRoot = %q, want %q", got, "src/main.go")
+	}
+}
+
+func TestSetRootIgnoresEmptyRoot(t *testing.T) {
+	m := NewMatcher()
+
+	m.SetRoot("/repo/one")
+	m.SetRoot("")
+
+	if got := m.relPathLocked("/repo/one/src/main.go"); got != "src/main.go" {
+		t.Fatalf("relPathLocked after empty SetRoot = %q, want %q", got, "src/main.go")
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"log"
+	"os"
 
 	"github.com/obby/file-watcher/internal/watcher"
 	pb "github.com/obby/file-watcher/proto/generated"
@@ -69,6 +70,37 @@ func (s *FileWatcherServer) StreamEvents(req *pb.EventRequest, stream grpc.Serve
 	}
 }
 
+// ExplainPath implements the ExplainPath RPC: it returns every watch/ignore
+// rule that matched req.Path, in evaluation order, so operators can debug
+// why a path is or isn't being watched.
+func (s *FileWatcherServer) ExplainPath(ctx context.Context, req *pb.ExplainRequest) (*pb.ExplainResponse, error) {
+	matcher := s.watcher.Matcher()
+	if matcher == nil {
+		return &pb.ExplainResponse{}, nil
+	}
+
+	isDir := false
+	if info, err := os.Stat(req.Path); err == nil {
+		isDir = info.IsDir()
+	}
+
+	trail := matcher.Explain(req.Path)
+	resp := &pb.ExplainResponse{
+		Rules:   make([]*pb.MatchedRule, 0, len(trail)),
+		Watched: matcher.IsWatched(req.Path, isDir) && !matcher.IsIgnored(req.Path, isDir),
+	}
+	for _, rule := range trail {
+		resp.Rules = append(resp.Rules, &pb.MatchedRule{
+			Pattern:  rule.Raw,
+			Source:   rule.Source,
+			Negate:   rule.Negate,
+			Anchored: rule.Anchored,
+			DirOnly:  rule.DirOnly,
+		})
+	}
+	return resp, nil
+}
+
 // UpdatePatterns implements the UpdatePatterns RPC
 func (s *FileWatcherServer) UpdatePatterns(ctx context.Context, req *pb.PatternUpdate) (*pb.PatternResponse, error) {
 	// Update patterns in matcher
@@ -76,4 +108,3 @@ func (s *FileWatcherServer) UpdatePatterns(ctx context.Context, req *pb.PatternU
 	// For now, return success - pattern updates can be handled by reloading .obbywatch/.obbyignore files
 	return &pb.PatternResponse{Success: true}, nil
 }
-
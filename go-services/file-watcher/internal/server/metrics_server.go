@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// MetricsService serves Prometheus metrics on Addr's /metrics endpoint,
+// implementing supervisor.Service.
+type MetricsService struct {
+	Addr string
+
+	server *http.Server
+}
+
+// Serve starts the metrics HTTP server and blocks until ctx is canceled or
+// the server fails on its own, gracefully shutting it down in the former
+// case.
+func (m *MetricsService) Serve(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	m.server = &http.Server{Addr: m.Addr, Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.WithField("addr", m.Addr).Info("metrics server starting")
+		errCh <- m.server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return m.server.Shutdown(context.Background())
+	case err := <-errCh:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	}
+}
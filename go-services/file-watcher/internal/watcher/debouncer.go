@@ -3,6 +3,8 @@ package watcher
 import (
 	"sync"
 	"time"
+
+	"github.com/obby/file-watcher/internal/metrics"
 )
 
 // Debouncer implements channel-based debouncing for file events
@@ -37,8 +39,10 @@ func (d *Debouncer) Process(key string, fn func()) {
 		fn()
 		d.mu.Lock()
 		delete(d.timers, key)
+		metrics.DebouncePending.Set(float64(len(d.timers)))
 		d.mu.Unlock()
 	})
+	metrics.DebouncePending.Set(float64(len(d.timers)))
 }
 
 // Stop stops all pending timers
@@ -50,5 +54,6 @@ func (d *Debouncer) Stop() {
 		timer.Stop()
 	}
 	d.timers = make(map[string]*time.Timer)
+	metrics.DebouncePending.Set(0)
 }
 
@@ -0,0 +1,170 @@
+package watcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/obby/file-watcher/internal/metrics"
+)
+
+// defaultPollInterval is how often a poller restats its root when
+// config.Config.PollIntervalMs isn't set.
+const defaultPollInterval = 2 * time.Second
+
+// fileState is the last-seen state of a polled file. mtime and size are
+// cheap to compare on every poll; hash is only recomputed when one of them
+// changes, and then decides whether to emit an event, so a touch with no
+// content change doesn't generate a spurious Modified event. An edit that
+// leaves both mtime and size unchanged (e.g. on a mount whose mtime
+// resolution is too coarse to see it) is not detected.
+type fileState struct {
+	mtime time.Time
+	size  int64
+	hash  string
+}
+
+// poller stats every file under a root on an interval, synthesizing
+// fsnotify-equivalent events for mounts where inotify can't be trusted to
+// report changes — DrvFS paths under WSL being the motivating case, where
+// a Windows-originated write is known to sometimes not raise an inotify
+// event at all.
+type poller struct {
+	fw       *FileWatcher
+	root     string
+	interval time.Duration
+
+	mu    sync.Mutex
+	state map[string]fileState
+}
+
+// newPoller creates a poller for root, ready for Run.
+func newPoller(fw *FileWatcher, root string, interval time.Duration) *poller {
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	return &poller{
+		fw:       fw,
+		root:     root,
+		interval: interval,
+		state:    make(map[string]fileState),
+	}
+}
+
+// Run polls root every p.interval until ctx is canceled, pushing
+// synthesized events through the same debouncer and pending channel
+// fsnotify-sourced events use.
+func (p *poller) Run(ctx context.Context) {
+	p.pollOnce(ctx)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.pollOnce(ctx)
+		}
+	}
+}
+
+// pollOnce stats every file under p.root, diffing against the previously
+// seen state to synthesize created/modified/deleted events, then updates
+// that state for next time.
+func (p *poller) pollOnce(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[string]bool, len(p.state))
+
+	err := filepath.Walk(p.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if !p.fw.shouldProcess(path) {
+			return nil
+		}
+		seen[path] = true
+		p.pollFile(ctx, path, info)
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).WithField("root", p.root).Warn("poll walk failed")
+	}
+
+	for path := range p.state {
+		if !seen[path] {
+			p.emit(ctx, path, EventDeleted)
+			delete(p.state, path)
+		}
+	}
+}
+
+// pollFile compares path's current stat (and, if that looks changed, its
+// hash) against its previously recorded fileState, emitting Created or
+// Modified as appropriate and recording the new state either way.
+func (p *poller) pollFile(ctx context.Context, path string, info os.FileInfo) {
+	prev, existed := p.state[path]
+	next := fileState{mtime: info.ModTime(), size: info.Size(), hash: prev.hash}
+
+	statUnchanged := existed && next.mtime.Equal(prev.mtime) && next.size == prev.size
+	if !statUnchanged {
+		if hash, err := fileHash(path); err == nil {
+			next.hash = hash
+		} else {
+			log.WithError(err).WithField("path", path).Warn("failed to hash polled file")
+		}
+	}
+
+	switch {
+	case !existed:
+		p.emit(ctx, path, EventCreated)
+	case !statUnchanged && next.hash != prev.hash:
+		p.emit(ctx, path, EventModified)
+	}
+
+	p.state[path] = next
+}
+
+// emit debounces and forwards a synthesized event the same way
+// FileWatcher.handleEvent does for fsnotify-sourced ones.
+func (p *poller) emit(ctx context.Context, path, eventType string) {
+	p.fw.debouncer.Process(path, func() {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		fileEvent := FileEvent{
+			Path:      path,
+			EventType: eventType,
+			Timestamp: time.Now(),
+		}
+
+		select {
+		case p.fw.pending <- fileEvent:
+		default:
+			metrics.EventsDroppedTotal.Inc()
+			log.WithField("path", path).Warn("pending channel full, dropping polled event")
+		}
+	})
+}
+
+// fileHash returns the hex-encoded SHA-256 of path's content.
+func fileHash(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -2,28 +2,56 @@ package watcher
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/obby/file-watcher/internal/metrics"
 	"github.com/obby/file-watcher/internal/patterns"
 )
 
+// defaultMaxWatchedDirs caps how many directories FileWatcher will register
+// with fsnotify; see SetMaxWatchedDirs. It exists so pointing the watcher
+// at something huge (a user's $HOME, say) fails with a clear error instead
+// of silently exhausting the inotify instance's watch descriptors.
+const defaultMaxWatchedDirs = 50000
+
+// ErrTooManyWatchedDirs is returned once registering another directory
+// would exceed FileWatcher's configured cap.
+var ErrTooManyWatchedDirs = errors.New("too many watched directories")
+
 // FileWatcher wraps fsnotify and provides debouncing and pattern matching
 type FileWatcher struct {
-	watcher   *fsnotify.Watcher
-	debouncer *Debouncer
-	matcher   *patterns.Matcher
-	events    chan FileEvent
-	errors    chan error
-	mu        sync.RWMutex
-	watching  map[string]bool
-	ctx       context.Context
-	cancel    context.CancelFunc
+	watcher        *fsnotify.Watcher
+	debouncer      *Debouncer
+	matcher        *patterns.Matcher
+	events         chan FileEvent
+	errors         chan error
+	pending        chan FileEvent
+	mu             sync.RWMutex
+	watching       map[string]bool
+	maxWatchedDirs int
+
+	// pollInterval and forcePoll configure the DrvFS/WSL (and forced,
+	// e.g. for network mounts) polling fallback; see shouldPoll.
+	pollInterval time.Duration
+	forcePoll    bool
+	// pollCtx/pollCancel bound every poller goroutine's lifetime to this
+	// FileWatcher's, canceled from Serve on shutdown. pollCancels holds
+	// each individual root's own derived cancel, so RemovePath can stop
+	// just that one poller.
+	pollCtx     context.Context
+	pollCancel  context.CancelFunc
+	pollCancels map[string]context.CancelFunc
 }
 
 // NewFileWatcher creates a new file watcher
@@ -33,34 +61,123 @@ func NewFileWatcher(debounceMs int, matcher *patterns.Matcher) (*FileWatcher, er
 		return nil, err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	preflightInotifyWatches(defaultMaxWatchedDirs)
+
+	pollCtx, pollCancel := context.WithCancel(context.Background())
 
 	return &FileWatcher{
-		watcher:   w,
-		debouncer: NewDebouncer(time.Duration(debounceMs) * time.Millisecond),
-		matcher:   matcher,
-		events:    make(chan FileEvent, 1000),
-		errors:    make(chan error, 10),
-		watching:  make(map[string]bool),
-		ctx:       ctx,
-		cancel:    cancel,
+		watcher:        w,
+		debouncer:      NewDebouncer(time.Duration(debounceMs) * time.Millisecond),
+		matcher:        matcher,
+		events:         make(chan FileEvent, 1000),
+		errors:         make(chan error, 10),
+		pending:        make(chan FileEvent, 1000),
+		watching:       make(map[string]bool),
+		maxWatchedDirs: defaultMaxWatchedDirs,
+		pollInterval:   defaultPollInterval,
+		pollCtx:        pollCtx,
+		pollCancel:     pollCancel,
+		pollCancels:    make(map[string]context.CancelFunc),
 	}, nil
 }
 
-// Start starts the file watcher
-func (fw *FileWatcher) Start() error {
-	go fw.processEvents()
-	return nil
+// SetMaxWatchedDirs overrides the directory cap addDirectoryRecursive
+// enforces (see defaultMaxWatchedDirs). n <= 0 disables the cap.
+func (fw *FileWatcher) SetMaxWatchedDirs(n int) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.maxWatchedDirs = n
+	preflightInotifyWatches(n)
+}
+
+// SetPollInterval overrides how often a polling root (see shouldPoll) is
+// restatted. interval <= 0 resets it to defaultPollInterval.
+func (fw *FileWatcher) SetPollInterval(interval time.Duration) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	fw.pollInterval = interval
+}
+
+// SetForcePoll forces every AddPath call to use the polling fallback
+// regardless of detectWSL/isDrvFsPath, useful for network mounts that have
+// the same unreliable-inotify problem DrvFS does.
+func (fw *FileWatcher) SetForcePoll(force bool) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.forcePoll = force
 }
 
-// Stop stops the file watcher
-func (fw *FileWatcher) Stop() error {
-	fw.cancel()
-	fw.debouncer.Stop()
-	err := fw.watcher.Close()
-	close(fw.events)
-	close(fw.errors)
-	return err
+// preflightInotifyWatches logs a warning if fs.inotify.max_user_watches
+// looks too low for maxWatchedDirs, which is otherwise a confusing source
+// of fsnotify.Add failures once a big tree fills up the host's inotify
+// instance. The sysctl file is Linux-only; its absence elsewhere (the read
+// failing) is silently treated as "nothing to check."
+func preflightInotifyWatches(maxWatchedDirs int) {
+	if maxWatchedDirs <= 0 {
+		return
+	}
+
+	data, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err != nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return
+	}
+
+	if limit < maxWatchedDirs {
+		log.WithFields(log.Fields{
+			"max_user_watches": limit,
+			"max_watched_dirs": maxWatchedDirs,
+		}).Warnf("fs.inotify.max_user_watches (%d) is lower than this watcher's directory cap (%d); large trees may silently stop getting new watches. Raise it with: sudo sysctl -w fs.inotify.max_user_watches=%d", limit, maxWatchedDirs, maxWatchedDirs)
+	}
+}
+
+// Serve runs the watcher's event loop until ctx is canceled, implementing
+// supervisor.Service. It's the sole writer of Events() and Errors(),
+// closing both right before it returns — which is what fixes the previous
+// design's send-on-closed-channel race, where Stop() closed the channels
+// from outside this goroutine while a debounce timer could still be about
+// to send on them. Debounced sends now land on an internal pending
+// channel that only this loop forwards from, so a timer firing after
+// Serve has returned just finds nothing reading it instead of panicking.
+func (fw *FileWatcher) Serve(ctx context.Context) error {
+	defer fw.watcher.Close()
+	defer fw.debouncer.Stop()
+	defer fw.pollCancel()
+	defer close(fw.errors)
+	defer close(fw.events)
+
+	for {
+		select {
+		case event := <-fw.watcher.Events:
+			fw.handleEvent(ctx, event)
+
+		case err := <-fw.watcher.Errors:
+			log.WithError(err).Error("watcher error")
+			select {
+			case fw.errors <- err:
+			default:
+			}
+
+		case fileEvent := <-fw.pending:
+			select {
+			case fw.events <- fileEvent:
+				metrics.EventsTotal.WithLabelValues(fileEvent.EventType).Inc()
+			default:
+				metrics.EventsDroppedTotal.Inc()
+				log.WithField("path", fileEvent.Path).Warn("event channel full, dropping event")
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
 // AddPath adds a path to watch
@@ -79,54 +196,163 @@ func (fw *FileWatcher) AddPath(path string) error {
 		return nil
 	}
 
+	if fw.matcher != nil {
+		fw.matcher.SetRoot(absPath)
+	}
+
+	if fw.shouldPoll(absPath) {
+		fw.watching[absPath] = true
+		fw.startPollingLocked(absPath)
+		log.WithField("path", absPath).Info("polling path (inotify unreliable for this mount)")
+		return nil
+	}
+
 	// Add to watcher
 	err = fw.watcher.Add(absPath)
 	if err != nil {
 		return err
 	}
 
-	fw.watching[absPath] = true
-	log.Printf("Watching path: %s", absPath)
+	log.WithField("path", absPath).Info("watching path")
 
-	// If it's a directory, add all subdirectories recursively
+	// If it's a directory, add all subdirectories recursively.
 	info, err := os.Stat(absPath)
 	if err == nil && info.IsDir() {
-		return fw.addDirectoryRecursive(absPath)
+		if err := fw.addDirectoryRecursiveLocked(absPath); err != nil {
+			// walkDir may have already marked absPath itself in fw.watching
+			// before failing on a descendant (e.g. ErrTooManyWatchedDirs),
+			// but the subtree isn't fully watched yet. Clear it so a
+			// retried AddPath(absPath) resumes the walk — skipping the
+			// subdirectories already added, retrying the rest — instead of
+			// short-circuiting forever on the "already watching" check
+			// above.
+			delete(fw.watching, absPath)
+			return err
+		}
+		fw.watching[absPath] = true
+		return nil
 	}
 
+	fw.watching[absPath] = true
 	return nil
 }
 
-// addDirectoryRecursive adds a directory and all subdirectories recursively
+// shouldPoll reports whether absPath should use the polling fallback
+// instead of fsnotify: either forcePoll is set, or absPath is on DrvFS
+// under WSL, where inotify is known to miss Windows-originated changes.
+func (fw *FileWatcher) shouldPoll(absPath string) bool {
+	return fw.forcePoll || (detectWSL() && isDrvFsPath(absPath))
+}
+
+// startPollingLocked spawns a poller goroutine for root, bound to a
+// sub-context of fw.pollCtx so RemovePath can stop just this root's
+// poller (and Serve's shutdown stops all of them at once). The caller
+// must hold fw.mu.
+func (fw *FileWatcher) startPollingLocked(root string) {
+	ctx, cancel := context.WithCancel(fw.pollCtx)
+	fw.pollCancels[root] = cancel
+	go newPoller(fw, root, fw.pollInterval).Run(ctx)
+}
+
+// addDirectoryRecursive locks fw.mu and adds dirPath and all its
+// subdirectories recursively. Used both by AddPath's initial walk and by
+// handleEvent when a Create event reports a new subdirectory — fsnotify
+// doesn't auto-watch directories created after the initial Add, so the
+// watcher has to notice and register them itself.
 func (fw *FileWatcher) addDirectoryRecursive(dirPath string) error {
-	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.addDirectoryRecursiveLocked(dirPath)
+}
 
-		if info.IsDir() {
-			// Check if should watch this directory
-			if fw.matcher != nil && !fw.matcher.IsWatched(path) {
-				return filepath.SkipDir
-			}
+// addDirectoryRecursiveLocked is addDirectoryRecursive's body for callers
+// (AddPath) that already hold fw.mu.
+func (fw *FileWatcher) addDirectoryRecursiveLocked(dirPath string) error {
+	return fw.walkDir(dirPath, make(map[inodeKey]bool))
+}
 
-			// Check if should ignore
-			if fw.matcher != nil && fw.matcher.IsIgnored(path) {
-				return filepath.SkipDir
-			}
+// inodeKey identifies a directory by device+inode, used to dedupe symlinks
+// so a cyclic symlink can't make walkDir recurse forever.
+type inodeKey struct {
+	dev, ino uint64
+}
 
-			// Add directory to watcher
-			if !fw.watching[path] {
-				if err := fw.watcher.Add(path); err != nil {
-					log.Printf("Error adding directory %s: %v", path, err)
-					return nil // Continue on error
-				}
-				fw.watching[path] = true
-			}
+// statInodeKey extracts dev+inode from a Stat result, if the platform's
+// FileInfo.Sys() supports it (true on Linux, which is all this repo runs
+// on).
+func statInodeKey(info os.FileInfo) (inodeKey, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return inodeKey{}, false
+	}
+	return inodeKey{dev: uint64(stat.Dev), ino: stat.Ino}, true
+}
+
+// walkDir recursively registers dirPath and its subdirectories with
+// fsnotify, honoring matcher.IsWatched/IsIgnored the same way this method
+// always has. Unlike filepath.Walk (which Lstats and so never descends
+// into a symlinked directory), walkDir follows symlinks via os.Stat,
+// tracking every directory it enters in visited so a symlink cycle is
+// walked at most once instead of recursing forever. The caller must hold
+// fw.mu.
+func (fw *FileWatcher) walkDir(dirPath string, visited map[inodeKey]bool) error {
+	info, err := os.Stat(dirPath) // Stat follows symlinks, unlike Lstat
+	if err != nil || !info.IsDir() {
+		return nil // Skip errors and non-directories, same as before
+	}
+
+	if key, ok := statInodeKey(info); ok {
+		if visited[key] {
+			return nil
+		}
+		visited[key] = true
+	}
+
+	if fw.matcher != nil {
+		if !fw.matcher.IsWatched(dirPath, true) || fw.matcher.IsIgnored(dirPath, true) {
+			return nil
+		}
+	}
+
+	if !fw.watching[dirPath] {
+		if fw.maxWatchedDirs > 0 && len(fw.watching) >= fw.maxWatchedDirs {
+			return fmt.Errorf("%w: refusing to watch %s (cap is %d)", ErrTooManyWatchedDirs, dirPath, fw.maxWatchedDirs)
 		}
+		if err := fw.watcher.Add(dirPath); err != nil {
+			log.WithError(err).WithField("path", dirPath).Error("error adding directory")
+			return nil // Continue on error
+		}
+		fw.watching[dirPath] = true
+	}
 
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
 		return nil
-	})
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && entry.Type()&os.ModeSymlink == 0 {
+			continue // plain files never need recursing into
+		}
+		if err := fw.walkDir(filepath.Join(dirPath, entry.Name()), visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forgetPath drops path from fw.watching after a Remove/Rename event.
+// fsnotify usually clears its own watch automatically once the underlying
+// inode is gone, but fw.watching is this type's own bookkeeping and
+// fsnotify has no way to tell it to do the same.
+func (fw *FileWatcher) forgetPath(path string) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+
+	if !fw.watching[path] {
+		return
+	}
+	_ = fw.watcher.Remove(path) // best-effort; the inode is likely already gone
+	delete(fw.watching, path)
 }
 
 // RemovePath removes a path from watching
@@ -143,6 +369,13 @@ func (fw *FileWatcher) RemovePath(path string) error {
 		return nil
 	}
 
+	if cancel, polling := fw.pollCancels[absPath]; polling {
+		cancel()
+		delete(fw.pollCancels, absPath)
+		delete(fw.watching, absPath)
+		return nil
+	}
+
 	err = fw.watcher.Remove(absPath)
 	if err != nil {
 		return err
@@ -152,26 +385,11 @@ func (fw *FileWatcher) RemovePath(path string) error {
 	return nil
 }
 
-// processEvents processes events from fsnotify
-func (fw *FileWatcher) processEvents() {
-	for {
-		select {
-		case event := <-fw.watcher.Events:
-			fw.handleEvent(event)
-		case err := <-fw.watcher.Errors:
-			log.Printf("watcher error: %v", err)
-			select {
-			case fw.errors <- err:
-			default:
-			}
-		case <-fw.ctx.Done():
-			return
-		}
-	}
-}
-
-// handleEvent handles a single fsnotify event
-func (fw *FileWatcher) handleEvent(event fsnotify.Event) {
+// handleEvent pattern-filters and debounces a single fsnotify event. The
+// debounce timer runs on its own goroutine and hands the resulting
+// FileEvent to fw.pending rather than fw.events directly, so Serve's loop
+// remains the only writer of fw.events.
+func (fw *FileWatcher) handleEvent(ctx context.Context, event fsnotify.Event) {
 	// Check if should process this event
 	if !fw.shouldProcess(event.Name) {
 		return
@@ -180,8 +398,29 @@ func (fw *FileWatcher) handleEvent(event fsnotify.Event) {
 	// Determine event type
 	eventType := fw.determineEventType(event)
 
+	// fsnotify only watches the directories it was told about at Add time,
+	// so a directory created after that point would otherwise go silently
+	// unwatched; a directory that's gone needs its bookkeeping dropped so
+	// it doesn't linger in fw.watching forever.
+	switch eventType {
+	case EventCreated:
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := fw.addDirectoryRecursive(event.Name); err != nil {
+				log.WithError(err).WithField("path", event.Name).Warn("failed to watch new subdirectory")
+			}
+		}
+	case EventDeleted, EventRenamed:
+		fw.forgetPath(event.Name)
+	}
+
 	// Debounce the event
 	fw.debouncer.Process(event.Name, func() {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
 		fileEvent := FileEvent{
 			Path:      event.Name,
 			EventType: eventType,
@@ -196,11 +435,10 @@ func (fw *FileWatcher) handleEvent(event fsnotify.Event) {
 		}
 
 		select {
-		case fw.events <- fileEvent:
-		case <-fw.ctx.Done():
-			return
+		case fw.pending <- fileEvent:
 		default:
-			log.Printf("Event channel full, dropping event: %s", event.Name)
+			metrics.EventsDroppedTotal.Inc()
+			log.WithField("path", event.Name).Warn("pending channel full, dropping event")
 		}
 	})
 }
@@ -211,13 +449,18 @@ func (fw *FileWatcher) shouldProcess(path string) bool {
 		return true
 	}
 
+	isDir := false
+	if info, err := os.Stat(path); err == nil {
+		isDir = info.IsDir()
+	}
+
 	// Check ignore patterns first
-	if fw.matcher.IsIgnored(path) {
+	if fw.matcher.IsIgnored(path, isDir) {
 		return false
 	}
 
 	// Check watch patterns (STRICT MODE)
-	if !fw.matcher.IsWatched(path) {
+	if !fw.matcher.IsWatched(path, isDir) {
 		return false
 	}
 
@@ -264,6 +507,13 @@ func (fw *FileWatcher) Errors() <-chan error {
 	return fw.errors
 }
 
+// Matcher returns the pattern matcher backing this watcher's watch/ignore
+// decisions, so callers like the ExplainPath RPC can debug why a given
+// path is or isn't being watched.
+func (fw *FileWatcher) Matcher() *patterns.Matcher {
+	return fw.matcher
+}
+
 // detectWSL detects if running in WSL
 func detectWSL() bool {
 	// Check for WSL indicators
@@ -281,4 +531,3 @@ func isDrvFsPath(path string) bool {
 	// DrvFS paths typically start with /mnt/
 	return strings.HasPrefix(path, "/mnt/")
 }
-
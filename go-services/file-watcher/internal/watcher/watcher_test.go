@@ -0,0 +1,55 @@
+package watcher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAddPathResumesAfterPartialFailure guards against AddPath marking a
+// root directory as fully watched before its recursive walk actually
+// finishes: if the walk fails partway through (here, by hitting
+// ErrTooManyWatchedDirs), a retried AddPath for the same root must resume
+// watching the subdirectories it didn't get to rather than silently
+// no-opping on the "already watching" check.
+func TestAddPathResumesAfterPartialFailure(t *testing.T) {
+	root := t.TempDir()
+	sub1 := filepath.Join(root, "sub1")
+	sub2 := filepath.Join(root, "sub2")
+	if err := os.Mkdir(sub1, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(sub2, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	fw, err := NewFileWatcher(50, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.SetMaxWatchedDirs(2) // root + one subdirectory fit; the other doesn't
+
+	if err := fw.AddPath(root); !errors.Is(err, ErrTooManyWatchedDirs) {
+		t.Fatalf("AddPath() error = %v, want ErrTooManyWatchedDirs", err)
+	}
+
+	absRoot, _ := filepath.Abs(root)
+	fw.mu.RLock()
+	watchingAfterFailure := fw.watching[absRoot]
+	fw.mu.RUnlock()
+	if watchingAfterFailure {
+		t.Fatal("root marked watching after a partial walk failure; retry would silently no-op")
+	}
+
+	fw.SetMaxWatchedDirs(0) // lift the cap so the retry can finish
+	if err := fw.AddPath(root); err != nil {
+		t.Fatalf("retried AddPath() error = %v, want nil", err)
+	}
+
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+	if !fw.watching[sub1] || !fw.watching[sub2] {
+		t.Fatalf("expected both subdirectories watched after retry, got sub1=%v sub2=%v", fw.watching[sub1], fw.watching[sub2])
+	}
+}
@@ -2,20 +2,34 @@ package main
 
 import (
 	"flag"
-	"log"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/obby/query-service/internal/database"
+	"github.com/obby/query-service/internal/interceptors"
 	"github.com/obby/query-service/internal/server"
 )
 
 func main() {
 	// Parse command line flags
 	var (
-		port = flag.Int("port", 50053, "Port to listen on")
-		dbPath = flag.String("db", "obby.db", "Path to SQLite database")
+		port       = flag.Int("port", 50053, "Port to listen on")
+		dbPath     = flag.String("db", "obby.db", "Path to SQLite database")
+		adminPort  = flag.Int("admin-port", 50063, "Port to serve Prometheus metrics on")
+		authTokens = flag.String("auth-tokens", "", "Comma-separated bearer tokens required on every RPC (auth disabled if empty)")
+		logLevel   = flag.String("log-level", "info", "Logging level (debug, info, warn, error)")
 	)
 	flag.Parse()
 
+	if lvl, err := log.ParseLevel(*logLevel); err == nil {
+		log.SetLevel(lvl)
+	} else {
+		log.Warnf("Unrecognized log-level %q, defaulting to info", *logLevel)
+	}
+
 	log.Printf("Starting Query Service on port %d with database: %s", *port, *dbPath)
 
 	// Initialize database
@@ -25,8 +39,34 @@ func main() {
 	}
 	defer db.Close()
 
+	// Build the shared logging/recovery/metrics/timeout/auth interceptor chain
+	interceptorCfg := interceptors.Config{
+		DefaultTimeout: 30 * time.Second,
+	}
+	if *authTokens != "" {
+		interceptorCfg.Authenticator = &interceptors.StaticTokenAuthenticator{
+			Tokens: parseTokens(*authTokens),
+		}
+	}
+
 	// Start gRPC server
-	if err := server.StartServer(*port, db); err != nil {
+	if err := server.StartServer(*port, db, server.Config{
+		Interceptors: interceptorCfg,
+		AdminAddr:    fmt.Sprintf(":%d", *adminPort),
+	}); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+}
+
+// parseTokens splits a comma-separated auth-tokens flag into the set
+// accepted by StaticTokenAuthenticator.
+func parseTokens(raw string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			tokens[t] = true
+		}
+	}
+	return tokens
 }
\ No newline at end of file
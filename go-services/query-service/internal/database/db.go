@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/obby/query-service/internal/metrics"
 )
 
 // DB wraps SQLite database connection
@@ -30,72 +33,44 @@ func NewDB(dbPath string) (*DB, error) {
 	
 	// Initialize FTS5 if needed
 	if err := db.initFTS(); err != nil {
-		log.Printf("Warning: FTS initialization failed: %v", err)
+		log.WithError(err).Warn("FTS initialization failed")
 	}
 
 	return db, nil
 }
 
-// initFTS initializes full-text search if not already present
+// observeQueryDuration records how long a database.DB operation took under
+// obby_db_query_duration_seconds, keyed by op. Call via defer at the top of
+// each exported query method: defer observeQueryDuration("op_name", time.Now()).
+func observeQueryDuration(op string, start time.Time) {
+	metrics.QueryDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// initFTS initializes full-text search if not already present. It's
+// intentionally NOT declared with fts5's "content=file_versions,
+// content_rowid=id" external-content mode: that mode stores only the token
+// index and re-reads the source text from file_versions.content for every
+// snippet()/highlight() call, which would force file_versions.content to
+// stay fully populated for every version just to keep search working, at
+// odds with InsertFileVersion (content-tracker) leaving it empty for
+// diff-only versions to avoid storing full bodies twice. A standalone fts5
+// table instead carries its own full-text copy, populated by
+// content-tracker's InsertFileVersion via indexForSearch rather than a
+// trigger on file_versions here. content-tracker's DB.initSearchIndex
+// creates the identical schema, so whichever service starts first wins.
 func (db *DB) initFTS() error {
-	// Check if file_versions_fts table exists
-	var count int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='file_versions_fts'").Scan(&count)
-	if err != nil {
+	if _, err := db.conn.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS file_versions_fts USING fts5(file_path, content)`); err != nil {
 		return err
 	}
 
-	if count == 0 {
-		// Create FTS5 virtual table
-		_, err := db.conn.Exec(`
-			CREATE VIRTUAL TABLE file_versions_fts USING fts5(
-				file_path, 
-				content,
-				content=file_versions,
-				content_rowid=id
-			)
-		`)
-		if err != nil {
-			return err
-		}
-
-		// Populate FTS table with existing data
-		_, err = db.conn.Exec(`
-			INSERT INTO file_versions_fts(file_path, content)
-			SELECT file_path, content FROM file_versions
-		`)
-		if err != nil {
-			return err
-		}
-
-		// Create triggers to keep FTS in sync
-		_, err = db.conn.Exec(`
-			CREATE TRIGGER file_versions_ai AFTER INSERT ON file_versions BEGIN
-				INSERT INTO file_versions_fts(file_path, content) VALUES (new.file_path, new.content);
-			END
-		`)
-		if err != nil {
-			return err
-		}
-
-		_, err = db.conn.Exec(`
-			CREATE TRIGGER file_versions_ad AFTER DELETE ON file_versions BEGIN
-				INSERT INTO file_versions_fts(file_versions_fts, rowid, file_path, content) VALUES('delete', old.id, old.file_path, old.content);
-			END
-		`)
-		if err != nil {
-			return err
-		}
-
-		_, err = db.conn.Exec(`
-			CREATE TRIGGER file_versions_au AFTER UPDATE ON file_versions BEGIN
-				INSERT INTO file_versions_fts(file_versions_fts, rowid, file_path, content) VALUES('delete', old.id, old.file_path, old.content);
-				INSERT INTO file_versions_fts(file_path, content) VALUES (new.file_path, new.content);
-			END
-		`)
-		if err != nil {
-			return err
-		}
+	// file_versions_vocab is an fts5vocab table over file_versions_fts,
+	// backing SuggestTerms autocomplete. Created unconditionally (IF NOT
+	// EXISTS) since it's cheap and has no data of its own to populate.
+	if _, err := db.conn.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS file_versions_vocab
+		USING fts5vocab(file_versions_fts, 'row')
+	`); err != nil {
+		return err
 	}
 
 	return nil
@@ -103,6 +78,8 @@ func (db *DB) initFTS() error {
 
 // GetRecentDiffs retrieves recent diffs with streaming support
 func (db *DB) GetRecentDiffs(ctx context.Context, limit int32) ([]DiffRecord, error) {
+	defer observeQueryDuration("get_recent_diffs", time.Now())
+
 	query := `
 		SELECT 
 			d.id,
@@ -148,10 +125,16 @@ func (db *DB) GetRecentDiffs(ctx context.Context, limit int32) ([]DiffRecord, er
 	return diffs, rows.Err()
 }
 
-// GetDiffsSince retrieves diffs since a specific timestamp
+// GetDiffsSince retrieves diffs recorded at or after timestamp (a unix
+// timestamp; see ParseSince for turning a --since flag value into one),
+// oldest first so a caller that switches to GetDiffsAfterID once it catches
+// up (e.g. a --follow stream) sees a gap-free, strictly increasing id
+// sequence.
 func (db *DB) GetDiffsSince(ctx context.Context, timestamp int64, limit int32) ([]DiffRecord, error) {
+	defer observeQueryDuration("get_diffs_since", time.Now())
+
 	query := `
-		SELECT 
+		SELECT
 			d.id,
 			d.file_path,
 			d.change_type,
@@ -162,8 +145,8 @@ func (db *DB) GetDiffsSince(ctx context.Context, timestamp int64, limit int32) (
 			d.content_hash,
 			d.size
 		FROM content_diffs d
-		WHERE d.timestamp > ?
-		ORDER BY d.timestamp DESC
+		WHERE d.timestamp >= ?
+		ORDER BY d.id ASC
 		LIMIT ?
 	`
 
@@ -196,8 +179,63 @@ func (db *DB) GetDiffsSince(ctx context.Context, timestamp int64, limit int32) (
 	return diffs, rows.Err()
 }
 
+// GetDiffsAfterID retrieves diffs inserted after afterID, oldest first. Used
+// to tail newly-inserted diffs by polling on the last ID seen once
+// GetDiffsSince's historical replay has caught up, instead of re-scanning by
+// timestamp.
+func (db *DB) GetDiffsAfterID(ctx context.Context, afterID int64, limit int32) ([]DiffRecord, error) {
+	defer observeQueryDuration("get_diffs_after_id", time.Now())
+
+	query := `
+		SELECT
+			d.id,
+			d.file_path,
+			d.change_type,
+			d.diff_content,
+			d.lines_added,
+			d.lines_removed,
+			d.timestamp,
+			d.content_hash,
+			d.size
+		FROM content_diffs d
+		WHERE d.id > ?
+		ORDER BY d.id ASC
+		LIMIT ?
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query diffs after id: %w", err)
+	}
+	defer rows.Close()
+
+	var diffs []DiffRecord
+	for rows.Next() {
+		var diff DiffRecord
+		err := rows.Scan(
+			&diff.Id,
+			&diff.FilePath,
+			&diff.ChangeType,
+			&diff.DiffContent,
+			&diff.LinesAdded,
+			&diff.LinesRemoved,
+			&diff.Timestamp,
+			&diff.ContentHash,
+			&diff.Size,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan diff record: %w", err)
+		}
+		diffs = append(diffs, diff)
+	}
+
+	return diffs, rows.Err()
+}
+
 // GetFileVersions retrieves version history for a specific file
 func (db *DB) GetFileVersions(ctx context.Context, filePath string, limit int32) ([]FileVersion, error) {
+	defer observeQueryDuration("get_file_versions", time.Now())
+
 	query := `
 		SELECT 
 			id,
@@ -236,52 +274,15 @@ func (db *DB) GetFileVersions(ctx context.Context, filePath string, limit int32)
 	return versions, rows.Err()
 }
 
-// SearchContent performs full-text search across file content
-func (db *DB) SearchContent(ctx context.Context, query string, limit int32) ([]SearchResult, error) {
-	// Use FTS5 for efficient search
-	ftsQuery := `
-		SELECT 
-			fv.id,
-			fv.file_path,
-			fv.content,
-			fv.timestamp,
-			snippet(file_versions_fts.content, 1, '<mark>', '</mark>', '...', 32) as highlighted,
-			rank() as rank
-		FROM file_versions fv
-		JOIN file_versions_fts fts ON fv.id = fts.rowid
-		WHERE file_versions_fts MATCH ?
-		ORDER BY rank
-		LIMIT ?
-	`
-
-	rows, err := db.conn.QueryContext(ctx, ftsQuery, query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search content: %w", err)
-	}
-	defer rows.Close()
-
-	var results []SearchResult
-	for rows.Next() {
-		var result SearchResult
-		err := rows.Scan(
-			&result.FilePath,
-			&result.Content,
-			&result.Timestamp,
-			&result.Highlighted,
-			&result.Rank,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan search result: %w", err)
-		}
-		results = append(results, result)
-	}
-
-	return results, rows.Err()
-}
-
 // GetTopicFiles retrieves files related to specific topics
 func (db *DB) GetTopicFiles(ctx context.Context, topic string, limit int32) ([]FileRecord, error) {
-	// Simple topic extraction from file paths and content
+	defer observeQueryDuration("get_topic_files", time.Now())
+
+	// Simple topic extraction from file paths and content. The content side
+	// matches against file_versions_fts.content (populated for every
+	// version, snapshot or not) rather than fv.content, which is only
+	// populated for snapshot versions; see content-tracker's
+	// InsertFileVersion/indexForSearch.
 	query := `
 		SELECT DISTINCT
 			fv.file_path,
@@ -289,8 +290,9 @@ func (db *DB) GetTopicFiles(ctx context.Context, topic string, limit int32) ([]F
 			fv.line_count,
 			fv.timestamp
 		FROM file_versions fv
-		WHERE fv.file_path LIKE ? 
-		   OR fv.content LIKE ?
+		LEFT JOIN file_versions_fts fts ON fv.id = fts.rowid
+		WHERE fv.file_path LIKE ?
+		   OR fts.content LIKE ?
 		ORDER BY fv.timestamp DESC
 		LIMIT ?
 	`
@@ -323,6 +325,8 @@ func (db *DB) GetTopicFiles(ctx context.Context, topic string, limit int32) ([]F
 
 // GetTimeAnalysis performs time-based activity analysis
 func (db *DB) GetTimeAnalysis(ctx context.Context, startTimestamp, endTimestamp int64) (*TimeAnalysisResult, error) {
+	defer observeQueryDuration("get_time_analysis", time.Now())
+
 	query := `
 		SELECT 
 			COUNT(*) as total_files_changed,
@@ -382,6 +386,8 @@ func (db *DB) GetTimeAnalysis(ctx context.Context, startTimestamp, endTimestamp
 
 // GetActivityStats generates activity statistics
 func (db *DB) GetActivityStats(ctx context.Context, startTimestamp, endTimestamp int64) (*ActivityStats, error) {
+	defer observeQueryDuration("get_activity_stats", time.Now())
+
 	query := `
 		SELECT 
 			COUNT(DISTINCT file_path) as files_changed,
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SearchOptions controls a SearchContent query: Query is parsed with a small
+// user-facing syntax ("exact phrase", term* prefixes, -excluded terms, and
+// path:glob scoping) and translated into FTS5 MATCH syntax; the rest tune
+// ranking, pagination, and how much work the query does.
+type SearchOptions struct {
+	Query string
+	// PathGlob restricts results to file paths matching a SQLite GLOB
+	// pattern, either set directly or extracted from a "path:" term in
+	// Query.
+	PathGlob string
+	// Since and Until, if non-zero, restrict results to file versions with
+	// a timestamp in [Since, Until).
+	Since int64
+	Until int64
+	// Limit and Offset page through results; Limit defaults to 20 if <= 0.
+	Limit  int32
+	Offset int32
+	// Highlight controls whether snippet() is computed into
+	// SearchResult.Highlighted at all, since it costs extra work the caller
+	// may not need (e.g. when only counting matches). FTS5's highlight() is
+	// not computed in either case.
+	Highlight bool
+}
+
+// SearchResults is the outcome of a SearchContent call: the page of Hits
+// plus enough metadata (Total, TookMs) for a client to render a typical
+// "N results in Xms" search summary.
+type SearchResults struct {
+	Hits   []SearchResult
+	Total  int64
+	TookMs int64
+}
+
+// pathTermPrefix is the "path:" scoping prefix recognized in a search query,
+// e.g. `path:src/**`.
+const pathTermPrefix = "path:"
+
+// defaultSearchLimit is used when SearchOptions.Limit is unset.
+const defaultSearchLimit = 20
+
+// SearchContent performs a ranked full-text search across file content using
+// FTS5, weighting file name matches above body matches via bm25(). The user
+// query in opts.Query is sanitized and translated into FTS5 MATCH syntax
+// before being run; opts.PathGlob (or a "path:" term extracted from the
+// query) further restricts results by file path.
+func (db *DB) SearchContent(ctx context.Context, opts SearchOptions) (*SearchResults, error) {
+	start := time.Now()
+	defer observeQueryDuration("search_content", start)
+
+	if opts.Limit <= 0 {
+		opts.Limit = defaultSearchLimit
+	}
+
+	matchQuery, pathGlob := translateSearchQuery(opts.Query)
+	if opts.PathGlob != "" {
+		pathGlob = opts.PathGlob
+	}
+	if matchQuery == "" {
+		return &SearchResults{}, nil
+	}
+
+	// fts.content, not fv.content: file_versions.content is only populated
+	// for snapshot versions (see content-tracker's InsertFileVersion), while
+	// file_versions_fts.content is indexed for every version regardless.
+	selectCols := "fv.id, fv.file_path, fts.content, fv.timestamp"
+	if opts.Highlight {
+		selectCols += `,
+			snippet(file_versions_fts, 1, '<mark>', '</mark>', '...', 32) as highlighted`
+	} else {
+		selectCols += `, '' as highlighted`
+	}
+
+	var conditions []string
+	args := []interface{}{matchQuery}
+	if pathGlob != "" {
+		conditions = append(conditions, "fv.file_path GLOB ?")
+		args = append(args, pathGlob)
+	}
+	if opts.Since > 0 {
+		conditions = append(conditions, "fv.timestamp >= ?")
+		args = append(args, opts.Since)
+	}
+	if opts.Until > 0 {
+		conditions = append(conditions, "fv.timestamp < ?")
+		args = append(args, opts.Until)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " AND " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s,
+			bm25(file_versions_fts, 10.0, 1.0) as rank
+		FROM file_versions fv
+		JOIN file_versions_fts fts ON fv.id = fts.rowid
+		WHERE file_versions_fts MATCH ?%s
+		ORDER BY rank
+		LIMIT ? OFFSET ?
+	`, selectCols, whereClause)
+
+	args = append(args, opts.Limit, opts.Offset)
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search content: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		var id int64
+		err := rows.Scan(
+			&id,
+			&result.FilePath,
+			&result.Content,
+			&result.Timestamp,
+			&result.Highlighted,
+			&result.Rank,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	total, err := db.countSearchMatches(ctx, matchQuery, pathGlob, opts.Since, opts.Until)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchResults{
+		Hits:   results,
+		Total:  total,
+		TookMs: time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// countSearchMatches returns the total number of rows matchQuery (plus the
+// same path/time filters SearchContent applied) matches, ignoring
+// pagination, so SearchResults.Total reflects the full result set rather
+// than just the returned page.
+func (db *DB) countSearchMatches(ctx context.Context, matchQuery, pathGlob string, since, until int64) (int64, error) {
+	var conditions []string
+	args := []interface{}{matchQuery}
+	if pathGlob != "" {
+		conditions = append(conditions, "fv.file_path GLOB ?")
+		args = append(args, pathGlob)
+	}
+	if since > 0 {
+		conditions = append(conditions, "fv.timestamp >= ?")
+		args = append(args, since)
+	}
+	if until > 0 {
+		conditions = append(conditions, "fv.timestamp < ?")
+		args = append(args, until)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " AND " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM file_versions fv
+		JOIN file_versions_fts fts ON fv.id = fts.rowid
+		WHERE file_versions_fts MATCH ?%s
+	`, whereClause)
+
+	var total int64
+	if err := db.conn.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count search matches: %w", err)
+	}
+	return total, nil
+}
+
+// SuggestTerms returns up to 10 distinct terms from the FTS5 index starting
+// with prefix, for autocomplete. It queries the fts5vocab virtual table
+// created alongside file_versions_fts.
+func (db *DB) SuggestTerms(ctx context.Context, prefix string) ([]string, error) {
+	defer observeQueryDuration("suggest_terms", time.Now())
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return nil, nil
+	}
+
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT term
+		FROM file_versions_vocab
+		WHERE term >= ? AND term < ? || x'FFFF'
+		ORDER BY term
+		LIMIT 10
+	`, prefix, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest terms: %w", err)
+	}
+	defer rows.Close()
+
+	var terms []string
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, fmt.Errorf("failed to scan suggested term: %w", err)
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
+
+// translateSearchQuery parses raw (the user-facing search syntax) into FTS5
+// MATCH syntax, pulling out a "path:" term as a separate GLOB pattern since
+// FTS5 has no notion of per-column path scoping here. Recognized syntax:
+//   - "exact phrase" is passed through as an FTS5 phrase
+//   - term* is passed through as an FTS5 prefix query
+//   - -excluded becomes "NOT excluded"
+//   - path:glob is extracted into the returned pathGlob and dropped from
+//     the MATCH query
+//   - any other bare term is quoted, so punctuation in it can't be
+//     misread as FTS5 operator syntax
+func translateSearchQuery(raw string) (matchQuery string, pathGlob string) {
+	var clauses []string
+
+	for _, token := range splitSearchTokens(raw) {
+		switch {
+		case strings.HasPrefix(token, pathTermPrefix):
+			pathGlob = strings.TrimPrefix(token, pathTermPrefix)
+		case strings.HasPrefix(token, `"`) && strings.HasSuffix(token, `"`) && len(token) > 1:
+			clauses = append(clauses, token)
+		case strings.HasPrefix(token, "-") && len(token) > 1:
+			clauses = append(clauses, "NOT "+quoteFTSTerm(strings.TrimPrefix(token, "-")))
+		case strings.HasSuffix(token, "*") && len(token) > 1:
+			clauses = append(clauses, quoteFTSTerm(strings.TrimSuffix(token, "*"))+"*")
+		default:
+			clauses = append(clauses, quoteFTSTerm(token))
+		}
+	}
+
+	return strings.Join(clauses, " "), pathGlob
+}
+
+// quoteFTSTerm wraps term in double quotes (escaping any embedded quote) so
+// it's treated as a literal FTS5 token rather than risking a collision with
+// FTS5 operator syntax (AND, OR, NOT, parentheses, colons, etc.).
+func quoteFTSTerm(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
+
+// splitSearchTokens splits raw on whitespace, keeping double-quoted phrases
+// (including their quotes) intact as a single token.
+func splitSearchTokens(raw string) []string {
+	var tokens []string
+	var b strings.Builder
+	inQuotes := false
+
+	flush := func() {
+		if b.Len() > 0 {
+			tokens = append(tokens, b.String())
+			b.Reset()
+		}
+	}
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			b.WriteRune(r)
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// absoluteSinceLayouts are tried in order when a --since value doesn't
+// parse as a relative duration.
+var absoluteSinceLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// dayWeekSuffix matches a bare count of days or weeks, e.g. "3d" or "2w".
+// time.ParseDuration only understands ns/us/ms/s/m/h, so ParseSince expands
+// these itself before falling back to it.
+var dayWeekSuffix = regexp.MustCompile(`^(\d+)([dw])$`)
+
+// ParseSince parses a --since value into a unix timestamp, accepting either
+// a relative duration in the past (time.ParseDuration's syntax, e.g. "10m",
+// "2h30m", plus "3d"/"2w" for days/weeks) or an absolute timestamp in
+// RFC3339, "2006-01-02 15:04:05", or "2006-01-02" form. Relative durations
+// are resolved against time.Now().
+func ParseSince(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty since value")
+	}
+
+	if d, ok := parseDayWeekDuration(s); ok {
+		return time.Now().Add(-d).Unix(), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d).Unix(), nil
+	}
+
+	for _, layout := range absoluteSinceLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid since value %q: not a duration or recognized timestamp", s)
+}
+
+// parseDayWeekDuration handles the "Nd"/"Nw" suffixes time.ParseDuration
+// doesn't, expressing N days or N weeks as 24h/7*24h multiples.
+func parseDayWeekDuration(s string) (time.Duration, bool) {
+	m := dayWeekSuffix.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, false
+	}
+
+	switch m[2] {
+	case "d":
+		return time.Duration(n) * 24 * time.Hour, true
+	case "w":
+		return time.Duration(n) * 7 * 24 * time.Hour, true
+	default:
+		return 0, false
+	}
+}
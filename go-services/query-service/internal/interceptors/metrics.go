@@ -0,0 +1,27 @@
+package interceptors
+
+import (
+	"log"
+	"net/http"
+
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+)
+
+// ServeMetrics registers Prometheus RPC metrics (call counts, latency
+// histograms, in-flight gauges) for server and blocks serving them on
+// addr's /metrics endpoint. Meant to run in its own goroutine on an
+// admin-only port, separate from the service's regular gRPC traffic.
+func ServeMetrics(addr string, server *grpc.Server) {
+	grpc_prometheus.Register(server)
+	grpc_prometheus.EnableHandlingTimeHistogram()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	log.Printf("Admin metrics server listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("admin metrics server failed: %v", err)
+	}
+}
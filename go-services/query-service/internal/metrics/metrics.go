@@ -0,0 +1,17 @@
+// Package metrics defines the Prometheus metrics the query service exposes
+// alongside its per-RPC metrics (see interceptors.ServeMetrics), for
+// tracking database query latency by logical operation.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// QueryDuration records how long each database.DB operation takes, keyed
+// by a short operation name (e.g. "get_recent_diffs", "search_content").
+var QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "obby_db_query_duration_seconds",
+	Help:    "Database query duration in seconds, by operation.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"op"})
@@ -8,14 +8,20 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
-	pb "github.com/obby/query-service/proto/generated"
 	"github.com/obby/query-service/internal/database"
+	"github.com/obby/query-service/internal/interceptors"
+	pb "github.com/obby/query-service/proto/generated"
 )
 
+// diffFollowPollInterval is how often GetDiffsSince re-queries content_diffs
+// for newly-inserted rows while req.Follow is set.
+const diffFollowPollInterval = 1 * time.Second
+
 // QueryServiceServer implements the gRPC QueryService interface
 type QueryServiceServer struct {
 	pb.UnimplementedQueryServiceServer
@@ -31,12 +37,9 @@ func NewQueryServiceServer(db *database.DB) *QueryServiceServer {
 
 // GetRecentDiffs implements GetRecentDiffs RPC (streaming)
 func (s *QueryServiceServer) GetRecentDiffs(req *pb.DiffQuery, stream pb.QueryService_GetRecentDiffsServer) error {
-	log.Printf("GetRecentDiffs called with limit: %d", req.GetLimit())
-
 	ctx := stream.Context()
 	diffs, err := s.db.GetRecentDiffs(ctx, req.GetLimit())
 	if err != nil {
-		log.Printf("Error getting recent diffs: %v", err)
 		return err
 	}
 
@@ -54,57 +57,99 @@ func (s *QueryServiceServer) GetRecentDiffs(req *pb.DiffQuery, stream pb.QuerySe
 			Size:         diff.Size,
 		}
 		if err := stream.Send(pbDiff); err != nil {
-			log.Printf("Error streaming diff: %v", err)
 			return err
 		}
 	}
 
-	log.Printf("Streamed %d recent diffs", len(diffs))
 	return nil
 }
 
-// GetDiffsSince implements GetDiffsSince RPC (streaming)
+// GetDiffsSince implements the GetDiffsSince RPC (streaming): it replays
+// every diff recorded since req.Since — a relative duration ("10m",
+// "2h30m", "3d") or an absolute RFC3339/"2006-01-02 [15:04:05]" timestamp,
+// parsed by database.ParseSince — oldest first, then, if req.Follow is
+// set, keeps the stream open and polls for newly-inserted diffs every
+// diffFollowPollInterval until the client disconnects. req.Since takes
+// precedence over the older req.Timestamp field when both are set, kept
+// for callers still passing a raw unix timestamp.
 func (s *QueryServiceServer) GetDiffsSince(req *pb.SinceQuery, stream pb.QueryService_GetDiffsSinceServer) error {
-	log.Printf("GetDiffsSince called with timestamp: %d, limit: %d", req.GetTimestamp(), req.GetLimit())
-
 	ctx := stream.Context()
-	diffs, err := s.db.GetDiffsSince(ctx, req.GetTimestamp(), req.GetLimit())
+
+	timestamp := req.GetTimestamp()
+	if req.GetSince() != "" {
+		sinceUnix, err := database.ParseSince(req.GetSince())
+		if err != nil {
+			return err
+		}
+		timestamp = sinceUnix
+	}
+
+	diffs, err := s.db.GetDiffsSince(ctx, timestamp, req.GetLimit())
 	if err != nil {
-		log.Printf("Error getting diffs since: %v", err)
 		return err
 	}
 
-	// Stream results
+	var lastID int64
 	for _, diff := range diffs {
-		pbDiff := &pb.DiffRecord{
-			Id:           diff.Id,
-			FilePath:     diff.FilePath,
-			ChangeType:   diff.ChangeType,
-			DiffContent:  diff.DiffContent,
-			LinesAdded:   int32(diff.LinesAdded),
-			LinesRemoved: int32(diff.LinesRemoved),
-			Timestamp:    diff.Timestamp,
-			ContentHash:  diff.ContentHash,
-			Size:         diff.Size,
-		}
-		if err := stream.Send(pbDiff); err != nil {
-			log.Printf("Error streaming diff: %v", err)
+		if err := stream.Send(toPBDiffRecord(diff)); err != nil {
 			return err
 		}
+		lastID = diff.Id
 	}
 
-	log.Printf("Streamed %d diffs since timestamp", len(diffs))
-	return nil
+	if !req.GetFollow() {
+		return nil
+	}
+
+	ticker := time.NewTicker(diffFollowPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			newDiffs, err := s.db.GetDiffsAfterID(ctx, lastID, req.GetLimit())
+			if err != nil {
+				return err
+			}
+			for _, diff := range newDiffs {
+				if diff.Timestamp < timestamp {
+					// Only reachable while lastID is still 0 (nothing in the
+					// replay window): skip history older than req.Since.
+					lastID = diff.Id
+					continue
+				}
+				if err := stream.Send(toPBDiffRecord(diff)); err != nil {
+					return err
+				}
+				lastID = diff.Id
+			}
+		}
+	}
+}
+
+// toPBDiffRecord converts a database.DiffRecord into its wire form.
+func toPBDiffRecord(d database.DiffRecord) *pb.DiffRecord {
+	return &pb.DiffRecord{
+		Id:           d.Id,
+		FilePath:     d.FilePath,
+		ChangeType:   d.ChangeType,
+		DiffContent:  d.DiffContent,
+		LinesAdded:   d.LinesAdded,
+		LinesRemoved: d.LinesRemoved,
+		Timestamp:    d.Timestamp,
+		ContentHash:  d.ContentHash,
+		Size:         d.Size,
+	}
 }
 
 // GetFileVersions implements GetFileVersions RPC (streaming)
 func (s *QueryServiceServer) GetFileVersions(req *pb.FileQuery, stream pb.QueryService_GetFileVersionsServer) error {
-	log.Printf("GetFileVersions called for path: %s, limit: %d", req.GetFilePath(), req.GetLimit())
-
 	ctx := stream.Context()
 	versions, err := s.db.GetFileVersions(ctx, req.GetFilePath(), req.GetLimit())
 	if err != nil {
-		log.Printf("Error getting file versions: %v", err)
 		return err
 	}
 
@@ -118,28 +163,41 @@ func (s *QueryServiceServer) GetFileVersions(req *pb.FileQuery, stream pb.QueryS
 			Timestamp:   version.Timestamp,
 		}
 		if err := stream.Send(pbVersion); err != nil {
-			log.Printf("Error streaming file version: %v", err)
 			return err
 		}
 	}
 
-	log.Printf("Streamed %d file versions for %s", len(versions), req.GetFilePath())
 	return nil
 }
 
-// SearchContent implements SearchContent RPC (streaming)
+// SearchContent implements SearchContent RPC (streaming). The first frame
+// sent is a summary carrying Total and TookMs so clients can render "N
+// results in Xms" before the hits themselves arrive.
 func (s *QueryServiceServer) SearchContent(req *pb.SearchQuery, stream pb.QueryService_SearchContentServer) error {
-	log.Printf("SearchContent called with query: %s, limit: %d", req.GetQuery(), req.GetLimit())
-
 	ctx := stream.Context()
-	results, err := s.db.SearchContent(ctx, req.GetQuery(), req.GetLimit())
+	results, err := s.db.SearchContent(ctx, database.SearchOptions{
+		Query:     req.GetQuery(),
+		PathGlob:  req.GetPathGlob(),
+		Since:     req.GetSince(),
+		Until:     req.GetUntil(),
+		Limit:     req.GetLimit(),
+		Offset:    req.GetOffset(),
+		Highlight: req.GetHighlight(),
+	})
 	if err != nil {
-		log.Printf("Error searching content: %v", err)
 		return err
 	}
 
-	// Stream results
-	for _, result := range results {
+	if err := stream.Send(&pb.SearchResult{
+		Summary: &pb.SearchSummary{
+			Total:  results.Total,
+			TookMs: results.TookMs,
+		},
+	}); err != nil {
+		return err
+	}
+
+	for _, result := range results.Hits {
 		pbResult := &pb.SearchResult{
 			FilePath:    result.FilePath,
 			Content:     result.Content,
@@ -147,23 +205,28 @@ func (s *QueryServiceServer) SearchContent(req *pb.SearchQuery, stream pb.QueryS
 			Rank:        float32(result.Rank),
 		}
 		if err := stream.Send(pbResult); err != nil {
-			log.Printf("Error streaming search result: %v", err)
 			return err
 		}
 	}
 
-	log.Printf("Streamed %d search results for query: %s", len(results), req.GetQuery())
 	return nil
 }
 
+// SuggestTerms implements SuggestTerms RPC, backing search-box autocomplete
+// from the FTS5 vocabulary.
+func (s *QueryServiceServer) SuggestTerms(ctx context.Context, req *pb.SuggestTermsRequest) (*pb.SuggestTermsResponse, error) {
+	terms, err := s.db.SuggestTerms(ctx, req.GetPrefix())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SuggestTermsResponse{Terms: terms}, nil
+}
+
 // GetTopicFiles implements GetTopicFiles RPC (streaming)
 func (s *QueryServiceServer) GetTopicFiles(req *pb.TopicQuery, stream pb.QueryService_GetTopicFilesServer) error {
-	log.Printf("GetTopicFiles called for topic: %s, limit: %d", req.GetTopic(), req.GetLimit())
-
 	ctx := stream.Context()
 	files, err := s.db.GetTopicFiles(ctx, req.GetTopic(), req.GetLimit())
 	if err != nil {
-		log.Printf("Error getting topic files: %v", err)
 		return err
 	}
 
@@ -175,22 +238,17 @@ func (s *QueryServiceServer) GetTopicFiles(req *pb.TopicQuery, stream pb.QuerySe
 			Size:         int64(file.LineCount), // Convert LineCount to Size for protobuf
 		}
 		if err := stream.Send(pbFile); err != nil {
-			log.Printf("Error streaming topic file: %v", err)
 			return err
 		}
 	}
 
-	log.Printf("Streamed %d files for topic: %s", len(files), req.GetTopic())
 	return nil
 }
 
 // GetTimeAnalysis implements GetTimeAnalysis RPC
 func (s *QueryServiceServer) GetTimeAnalysis(ctx context.Context, req *pb.TimeQuery) (*pb.TimeAnalysisResult, error) {
-	log.Printf("GetTimeAnalysis called with start: %d, end: %d", req.GetStartTimestamp(), req.GetEndTimestamp())
-
 	result, err := s.db.GetTimeAnalysis(ctx, req.GetStartTimestamp(), req.GetEndTimestamp())
 	if err != nil {
-		log.Printf("Error getting time analysis: %v", err)
 		return nil, err
 	}
 
@@ -203,17 +261,13 @@ func (s *QueryServiceServer) GetTimeAnalysis(ctx context.Context, req *pb.TimeQu
 		TopKeywords:       result.TopKeywords,
 	}
 
-	log.Printf("Time analysis completed: %d changes", result.TotalFilesChanged)
 	return pbResult, nil
 }
 
 // GetActivityStats implements GetActivityStats RPC
 func (s *QueryServiceServer) GetActivityStats(ctx context.Context, req *pb.StatsQuery) (*pb.ActivityStats, error) {
-	log.Printf("GetActivityStats called with start: %d, end: %d", req.GetStartTimestamp(), req.GetEndTimestamp())
-
 	stats, err := s.db.GetActivityStats(ctx, req.GetStartTimestamp(), req.GetEndTimestamp())
 	if err != nil {
-		log.Printf("Error getting activity stats: %v", err)
 		return nil, err
 	}
 
@@ -226,23 +280,35 @@ func (s *QueryServiceServer) GetActivityStats(ctx context.Context, req *pb.Stats
 		AvgChangesPerFile: stats.AvgChangesPerFile,
 	}
 
-	log.Printf("Activity stats completed: %d events", stats.TotalChanges)
 	return pbStats, nil
 }
 
+// Config configures the gRPC server beyond the listen port and database,
+// namely the interceptor chain and the admin port that exposes metrics.
+type Config struct {
+	Interceptors interceptors.Config
+	// AdminAddr is the address Prometheus metrics are served on, e.g.
+	// ":50063". Empty disables the admin metrics server.
+	AdminAddr string
+}
+
 // StartServer starts the gRPC server
-func StartServer(port int, db *database.DB) error {
+func StartServer(port int, db *database.DB, cfg Config) error {
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		return fmt.Errorf("failed to listen on port %d: %w", port, err)
 	}
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(interceptors.ServerOptions(cfg.Interceptors)...)
 	pb.RegisterQueryServiceServer(s, NewQueryServiceServer(db))
 
 	// Enable reflection for development
 	reflection.Register(s)
 
+	if cfg.AdminAddr != "" {
+		go interceptors.ServeMetrics(cfg.AdminAddr, s)
+	}
+
 	log.Printf("Query Service starting on port %d", port)
 
 	// Graceful shutdown
@@ -4,71 +4,113 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
 	"os/signal"
-	"sync"
+	"strings"
 	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/obby/sse-hub/internal/hub"
 	"github.com/obby/sse-hub/internal/server"
+	"github.com/obby/sse-hub/internal/supervisor"
 )
 
 func main() {
 	// Parse command line flags
 	var (
-		grpcPort = flag.Int("grpc-port", 50054, "Port for gRPC server")
-		httpPort = flag.Int("http-port", 8080, "Port for HTTP server")
+		grpcPort         = flag.Int("grpc-port", 50054, "Port for gRPC server")
+		httpPort         = flag.Int("http-port", 8080, "Port for HTTP server")
+		shutdownTimeout  = flag.Duration("shutdown-timeout", 30*time.Second, "Max time to wait for connections to drain on shutdown")
+		replayBufferSize = flag.Int("replay-buffer-size", 256, "Messages retained per topic for Last-Event-ID resume")
+		maxClientsPerIP  = flag.Int("max-clients-per-ip", 100, "Max simultaneous connections allowed from one IP (0 disables the cap)")
+		connectRate      = flag.Float64("connect-rate", 2.0, "Max new SSE connections per second allowed from one IP")
+		connectBurst     = flag.Int("connect-burst", 5, "Burst of new SSE connections allowed from one IP above connect-rate")
+
+		replicationNodeID     = flag.String("replication-node-id", "", "Unique ID for this node, used to tag and dedupe replicated messages")
+		replicationListenAddr = flag.String("replication-listen", "", "Address to accept inbound peer replication connections on (empty disables replication)")
+		replicationPeers      = flag.String("replication-peers", "", "Comma-separated addresses of peer nodes to replicate with")
+		replicationCertFile   = flag.String("replication-cert", "", "TLS certificate presented to replication peers")
+		replicationKeyFile    = flag.String("replication-key", "", "TLS key for replication-cert")
+		replicationCAFile     = flag.String("replication-ca", "", "CA bundle used to verify replication peers")
+		replicationOutboxSize = flag.Int("replication-outbox-size", 256, "Max buffered messages per peer before new ones are dropped")
+		logLevel              = flag.String("log-level", "info", "Logging level (debug, info, warn, error)")
 	)
 	flag.Parse()
 
+	if lvl, err := log.ParseLevel(*logLevel); err == nil {
+		log.SetLevel(lvl)
+	} else {
+		log.Warnf("Unrecognized log-level %q, defaulting to info", *logLevel)
+	}
+
 	log.Printf("Starting SSE Hub Service")
 	log.Printf("gRPC Port: %d, HTTP Port: %d", *grpcPort, *httpPort)
 
-	// Create SSE hub
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	sseHub := hub.NewSSEHub()
+	sseHub.SetReplayBufferSize(*replayBufferSize)
+	sseHub.SetMaxClientsPerIP(*maxClientsPerIP)
 
-	// Start gRPC server in goroutine
-	go func() {
-		if err := server.StartGRPCServer(*grpcPort, sseHub); err != nil {
-			log.Printf("gRPC server failed: %v", err)
+	if *replicationListenAddr != "" {
+		var peers []string
+		for _, addr := range strings.Split(*replicationPeers, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				peers = append(peers, addr)
+			}
 		}
-	}()
 
-	// Start HTTP server in goroutine
+		if _, err := sseHub.EnableReplication(ctx, hub.ReplicationConfig{
+			NodeID:     *replicationNodeID,
+			ListenAddr: *replicationListenAddr,
+			Peers:      peers,
+			CertFile:   *replicationCertFile,
+			KeyFile:    *replicationKeyFile,
+			CAFile:     *replicationCAFile,
+			OutboxSize: *replicationOutboxSize,
+		}); err != nil {
+			log.Fatalf("Failed to start replication: %v", err)
+		}
+		log.Printf("Replication enabled: node %s listening on %s, peers: %v", *replicationNodeID, *replicationListenAddr, peers)
+	}
+
+	grpcServer := server.NewGRPCServer(sseHub)
 	httpServer := server.NewHTTPServer(sseHub, *httpPort)
+	httpServer.SetShutdownTimeout(*shutdownTimeout)
+	httpServer.SetConnectRateLimit(*connectRate, *connectBurst)
+
+	sup := supervisor.New()
+	// The hub's dispatch loop goes first so Broadcast/Register calls from
+	// the transports below always have somewhere to land.
+	sup.Add("hub", sseHub)
+	sup.Add("grpc", &server.GRPCService{Server: grpcServer, Port: *grpcPort})
+	sup.Add("http", httpServer)
+
+	done := make(chan struct{})
 	go func() {
-		if err := httpServer.Start(); err != nil && err != http.ErrServerClosed {
-			log.Printf("HTTP server failed: %v", err)
+		defer close(done)
+		if err := sup.Serve(ctx); err != nil {
+			log.Printf("supervisor exited: %v", err)
 		}
 	}()
 
-	// Wait for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start hub in background
-	go sseHub.Run(ctx)
-
 	log.Printf("SSE Hub Service is running...")
 	<-sigChan
 
-	log.Printf("Shutting down SSE Hub Service...")
-	
-	// Stop both servers
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		// Note: HTTP server shutdown would need proper context management
-		// For now, we rely on OS signal handling
-	}()
+	log.Printf("Shutting down SSE Hub Service (timeout: %s)...", *shutdownTimeout)
+
+	// Cancel the hub's context first so it broadcasts a final "shutdown"
+	// SSE frame to every connected client and closes their channels,
+	// giving them a chance to reconnect elsewhere before the transports
+	// themselves stop.
+	cancel()
+	<-done
 
-	// Wait for graceful shutdown
-	wg.Wait()
 	fmt.Println("SSE Hub Service stopped")
-}
\ No newline at end of file
+}
@@ -0,0 +1,164 @@
+package hub
+
+import (
+	"sync"
+	"time"
+)
+
+// OverflowPolicy controls what a leakyBucket does when Pour would push its
+// level past CapacityBytes.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drops the message immediately and increments Dropped.
+	// The default: a single slow client loses messages instead of slowing
+	// down or disconnecting.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock blocks the caller until the bucket has drained enough
+	// to accept the message or BlockDeadline elapses, whichever is first;
+	// on deadline it falls back to OverflowDrop's behavior.
+	OverflowBlock
+	// OverflowDisconnect tells the caller to disconnect the client instead
+	// of delivering the message.
+	OverflowDisconnect
+)
+
+// bucketDrainPollInterval is how often a blocked Pour rechecks the bucket
+// level while waiting for it to drain.
+const bucketDrainPollInterval = 20 * time.Millisecond
+
+// BucketConfig configures a per-client leaky bucket.
+type BucketConfig struct {
+	// CapacityBytes is the maximum number of bytes the bucket can hold
+	// before Policy kicks in.
+	CapacityBytes int64
+	// CapacityMessages bounds the client's Send channel buffer — a
+	// separate cap from CapacityBytes, so a stream of many small messages
+	// can't starve delivery even while comfortably under its byte budget.
+	CapacityMessages int
+	// DrainRate is how many bytes per second leak out of the bucket.
+	DrainRate int64
+	// Policy governs what happens when Pour would overflow the bucket.
+	Policy OverflowPolicy
+	// BlockDeadline bounds how long OverflowBlock waits for room before
+	// giving up and dropping the message.
+	BlockDeadline time.Duration
+}
+
+// DefaultBucketConfig returns the bucket configuration used for clients
+// that don't request their own: a 1MiB byte budget draining at 256KiB/s, a
+// 256-message send buffer, and OverflowDrop.
+func DefaultBucketConfig() BucketConfig {
+	return BucketConfig{
+		CapacityBytes:    1 << 20,
+		CapacityMessages: 256,
+		DrainRate:        256 << 10,
+		Policy:           OverflowDrop,
+		BlockDeadline:    2 * time.Second,
+	}
+}
+
+// BucketStats is a point-in-time snapshot of a client's leaky bucket,
+// surfaced by the ClientStats RPC so operators can spot slow consumers.
+type BucketStats struct {
+	QueueBytes    int64
+	QueueMessages int
+	Dropped       uint64
+	LastDrain     time.Time
+}
+
+// leakyBucket rate-limits one client's inbound message stream by bytes:
+// Pour adds to the level, and the level continuously leaks out at
+// DrainRate bytes/sec between pours — a classic leaky bucket, never
+// bursting upward on its own between calls.
+type leakyBucket struct {
+	cfg BucketConfig
+
+	mu        sync.Mutex
+	bytes     int64
+	dropped   uint64
+	lastDrain time.Time
+}
+
+func newLeakyBucket(cfg BucketConfig) *leakyBucket {
+	return &leakyBucket{cfg: cfg, lastDrain: time.Now()}
+}
+
+// Pour attempts to add n bytes to the bucket. It reports whether the
+// caller should go on to deliver the message: true for an accepted pour
+// (including one that had to wait under OverflowBlock), false if the
+// message was dropped or the client should be disconnected instead
+// (disconnect is signaled through disconnect, not the bool, since the
+// caller needs to react differently to it).
+func (b *leakyBucket) Pour(n int) (accepted bool, disconnect bool) {
+	deadline := time.Time{}
+	if b.cfg.Policy == OverflowBlock && b.cfg.BlockDeadline > 0 {
+		deadline = time.Now().Add(b.cfg.BlockDeadline)
+	}
+
+	for {
+		b.mu.Lock()
+		b.drainLocked()
+
+		if b.bytes+int64(n) <= b.cfg.CapacityBytes {
+			b.bytes += int64(n)
+			b.mu.Unlock()
+			return true, false
+		}
+
+		switch b.cfg.Policy {
+		case OverflowDisconnect:
+			b.mu.Unlock()
+			return false, true
+
+		case OverflowBlock:
+			b.mu.Unlock()
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				b.mu.Lock()
+				b.dropped++
+				b.mu.Unlock()
+				return false, false
+			}
+			time.Sleep(bucketDrainPollInterval)
+			continue
+
+		default: // OverflowDrop
+			b.dropped++
+			b.mu.Unlock()
+			return false, false
+		}
+	}
+}
+
+// drainLocked leaks bytes out of the bucket proportional to elapsed time
+// since the last drain. Called with mu held.
+func (b *leakyBucket) drainLocked() {
+	now := time.Now()
+	elapsed := now.Sub(b.lastDrain).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	leaked := int64(elapsed * float64(b.cfg.DrainRate))
+	if leaked > 0 {
+		b.bytes -= leaked
+		if b.bytes < 0 {
+			b.bytes = 0
+		}
+		b.lastDrain = now
+	}
+}
+
+// Stats returns a snapshot of the bucket's current level and drop count.
+// queueMessages is supplied by the caller since the bucket itself doesn't
+// own the client's Send channel.
+func (b *leakyBucket) Stats(queueMessages int) BucketStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BucketStats{
+		QueueBytes:    b.bytes,
+		QueueMessages: queueMessages,
+		Dropped:       b.dropped,
+		LastDrain:     b.lastDrain,
+	}
+}
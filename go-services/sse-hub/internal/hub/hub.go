@@ -2,16 +2,37 @@ package hub
 
 import (
 	"context"
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/obby/sse-hub/internal/metrics"
 )
 
+// ErrTooManyConnections is returned by Register when ip already has
+// maxClientsPerIP connections registered.
+var ErrTooManyConnections = errors.New("too many connections from this ip")
+
 // Message represents an SSE message
 type Message struct {
 	Event string
 	Topic string
 	Data  string
+
+	// OriginNode and Seq uniquely identify this message fleet-wide: Broadcast
+	// stamps every locally-published message with this hub's node ID and
+	// the next value from its monotonic sequence counter, so a Replicator
+	// merging the same message in from two different peer connections (or
+	// replaying after a reconnect) can dedupe on (OriginNode, Seq) instead
+	// of re-broadcasting it.
+	OriginNode string
+	Seq        uint64
 }
 
 // Client represents an SSE client connection
@@ -21,40 +42,118 @@ type Client struct {
 	Send   chan Message
 	Topics map[string]bool
 	mu     sync.RWMutex
+
+	matchers []*topicMatcher
+
+	ip     string
+	bucket *leakyBucket
+
+	// outbox serializes delivery to this client: Serve's broadcast case
+	// enqueues onto it (never blocking, so one slow client can't stall the
+	// dispatch loop for everyone else) and runClientDispatcher drains it on
+	// a single goroutine, so two messages broadcast back to back can never
+	// reach client.Send out of order the way firing a bare "go h.deliver"
+	// per message could.
+	outbox chan Message
 }
 
+// defaultMaxClientsPerIP bounds how many simultaneous connections a single
+// IP may hold, when SetMaxClientsPerIP hasn't been called to override it.
+// Zero would mean "unlimited", so the default is a generous but finite cap.
+const defaultMaxClientsPerIP = 100
+
 // SSEHub manages SSE client connections and broadcasting
 type SSEHub struct {
 	clients    map[string]*Client
+	clientIPs  map[string]int
 	broadcast   chan Message
-	register    chan *Client
+	register    chan *clientRegistration
 	unregister  chan *Client
 	mu          sync.RWMutex
+
+	nodeID     string
+	seq        uint64
+	replicator *Replicator
+
+	replay          *replayStore
+	maxClientsPerIP int
+}
+
+// clientRegistration pairs a connecting client with the IP it connected
+// from, so Serve's register case can enforce the per-IP cap and the caller
+// can learn whether registration actually succeeded.
+type clientRegistration struct {
+	client *Client
+	ip     string
+	result chan error
 }
 
 // NewSSEHub creates a new SSE hub
 func NewSSEHub() *SSEHub {
 	return &SSEHub{
-		clients:    make(map[string]*Client),
-		broadcast:  make(chan Message, 100),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
+		clients:         make(map[string]*Client),
+		clientIPs:       make(map[string]int),
+		broadcast:       make(chan Message, 100),
+		register:        make(chan *clientRegistration),
+		unregister:      make(chan *Client),
+		replay:          newReplayStore(defaultReplayBufferSize),
+		maxClientsPerIP: defaultMaxClientsPerIP,
 	}
 }
 
-// NewClient creates a new client
+// SetMaxClientsPerIP overrides how many simultaneous connections a single
+// IP may hold; n <= 0 disables the cap.
+func (h *SSEHub) SetMaxClientsPerIP(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.maxClientsPerIP = n
+}
+
+// SetReplayBufferSize sets how many messages each topic's replay ring
+// buffer retains for Last-Event-ID resume. Call it before the hub starts
+// accepting Broadcast calls; it only affects buffers created afterward.
+func (h *SSEHub) SetReplayBufferSize(size int) {
+	h.replay.setCapacity(size)
+}
+
+// ReplaySince returns, oldest first, every buffered message on a topic
+// client is subscribed to with a Seq greater than afterSeq — the messages
+// client missed while disconnected, for resuming from a Last-Event-ID.
+func (h *SSEHub) ReplaySince(client *Client, afterSeq uint64) []Message {
+	return h.replay.since(afterSeq, client.IsSubscribed)
+}
+
+// NewClient creates a new client with the default bucket config. Use
+// NewClientWithBucket to give a client its own capacity/rate/policy.
 func (h *SSEHub) NewClient(ctx context.Context) *Client {
+	return h.NewClientWithBucket(ctx, DefaultBucketConfig())
+}
+
+// NewClientWithBucket creates a new client whose Send buffer and
+// leaky-bucket backpressure are sized and governed by cfg.
+func (h *SSEHub) NewClientWithBucket(ctx context.Context, cfg BucketConfig) *Client {
 	return &Client{
 		ID:     generateClientID(),
 		Hub:    h,
-		Send:   make(chan Message, 256),
+		Send:   make(chan Message, cfg.CapacityMessages),
 		Topics: make(map[string]bool),
+		bucket: newLeakyBucket(cfg),
+		outbox: make(chan Message, cfg.CapacityMessages),
 	}
 }
 
-// Register registers a client
-func (h *SSEHub) Register(client *Client) {
-	h.register <- client
+// Register registers client as connecting from ip, enforcing the per-IP
+// connection cap set by SetMaxClientsPerIP. It returns
+// ErrTooManyConnections without registering the client if ip is already at
+// the cap; callers (HTTP and gRPC transports) should surface that as a
+// rate-limit error (e.g. HTTP 429 or codes.ResourceExhausted) rather than
+// accepting the connection.
+func (h *SSEHub) Register(client *Client, ip string) error {
+	client.ip = ip
+
+	reg := &clientRegistration{client: client, ip: ip, result: make(chan error, 1)}
+	h.register <- reg
+	return <-reg.result
 }
 
 // Unregister unregisters a client
@@ -62,38 +161,135 @@ func (h *SSEHub) Unregister(client *Client) {
 	h.unregister <- client
 }
 
-// Broadcast broadcasts a message to all subscribed clients
+// Broadcast publishes a locally-originated message: it stamps msg with this
+// node's ID and the next sequence number, fans it out to subscribed local
+// clients, and — if EnableReplication has been called — enqueues it to
+// every replication peer so the fleet converges on the same set of
+// messages.
 func (h *SSEHub) Broadcast(msg Message) {
+	h.mu.RLock()
+	msg.OriginNode = h.nodeID
+	replicator := h.replicator
+	h.mu.RUnlock()
+
+	msg.Seq = atomic.AddUint64(&h.seq, 1)
+
+	h.push(msg)
+	if replicator != nil {
+		replicator.enqueue(msg)
+	}
+}
+
+// push delivers msg to local clients only, without stamping origin
+// metadata or fanning it out to replication peers. Broadcast uses it for
+// locally-originated messages after stamping them; Replicator.merge uses
+// it directly for messages that already carry a peer's origin metadata. It
+// also records msg in the topic's replay buffer so a client that
+// disconnects can resume from its Seq via ReplaySince.
+func (h *SSEHub) push(msg Message) {
+	h.replay.record(msg)
 	h.broadcast <- msg
 }
 
-// Run runs the hub's main loop
-func (h *SSEHub) Run(ctx context.Context) {
+// EnableReplication starts a Replicator for h from cfg: a replicationSender
+// per peer in cfg.Peers and a replicationListener accepting inbound peer
+// connections on cfg.ListenAddr. Call it once, before the hub starts
+// accepting Publish calls, so every Broadcast message is stamped with
+// cfg.NodeID. The returned Replicator is also reachable via
+// ReplicationPeerStatus for the ReplicationStatus RPC.
+func (h *SSEHub) EnableReplication(ctx context.Context, cfg ReplicationConfig) (*Replicator, error) {
+	r, err := NewReplicator(h, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	h.nodeID = cfg.NodeID
+	h.replicator = r
+	h.mu.Unlock()
+
+	return r, nil
+}
+
+// ReplicationPeerStatus returns a point-in-time snapshot of every
+// replication peer's connection state and outbox depth, or nil if
+// EnableReplication hasn't been called.
+func (h *SSEHub) ReplicationPeerStatus() []PeerStatus {
+	h.mu.RLock()
+	r := h.replicator
+	h.mu.RUnlock()
+
+	if r == nil {
+		return nil
+	}
+	return r.Status()
+}
+
+// Serve runs the hub's main dispatch loop until ctx is canceled,
+// implementing supervisor.Service: it registers/unregisters clients, fans
+// out broadcast messages, and on cancellation sends every connected client
+// a final "shutdown" frame before returning.
+func (h *SSEHub) Serve(ctx context.Context) error {
 	for {
 		select {
-		case client := <-h.register:
+		case reg := <-h.register:
 			h.mu.Lock()
-			h.clients[client.ID] = client
+			if h.maxClientsPerIP > 0 && reg.ip != "" && h.clientIPs[reg.ip] >= h.maxClientsPerIP {
+				h.mu.Unlock()
+				log.WithField("ip", reg.ip).Warnf("rejected client: already at %d connections", h.maxClientsPerIP)
+				reg.result <- ErrTooManyConnections
+				continue
+			}
+
+			h.clients[reg.client.ID] = reg.client
+			if reg.ip != "" {
+				h.clientIPs[reg.ip]++
+			}
 			h.mu.Unlock()
-			log.Printf("Client registered: %s (total: %d)", client.ID, len(h.clients))
+			go h.runClientDispatcher(reg.client)
+			metrics.ClientsConnected.Inc()
+			log.WithField("client", reg.client.ID).Infof("client registered (total: %d)", len(h.clients))
+			reg.result <- nil
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, exists := h.clients[client.ID]; exists {
+			_, exists := h.clients[client.ID]
+			if exists {
 				delete(h.clients, client.ID)
-				close(client.Send)
+				// client.outbox, not client.Send, is closed here: Send is
+				// only ever written to by this client's own
+				// runClientDispatcher goroutine, so that goroutine closes
+				// it after draining outbox, rather than racing a close
+				// here against a send still in flight there.
+				close(client.outbox)
+				if client.ip != "" {
+					h.clientIPs[client.ip]--
+					if h.clientIPs[client.ip] <= 0 {
+						delete(h.clientIPs, client.ip)
+					}
+				}
 			}
 			h.mu.Unlock()
-			log.Printf("Client unregistered: %s", client.ID)
+			if exists {
+				metrics.ClientsConnected.Dec()
+			}
+			log.WithField("client", client.ID).Info("client unregistered")
 
 		case message := <-h.broadcast:
+			metrics.MessagesBroadcastTotal.Inc()
 			h.mu.RLock()
 			for _, client := range h.clients {
 				if client.IsSubscribed(message.Topic) {
 					select {
-					case client.Send <- message:
+					case client.outbox <- message:
 					default:
-						// Client buffer full, disconnect slow client
+						// The dispatcher goroutine itself is stalled, not
+						// just the leaky bucket it governs; disconnect on
+						// its own goroutine so this doesn't block Serve's
+						// loop waiting on h.unregister.
 						go h.unregisterClient(client)
 					}
 				}
@@ -102,36 +298,114 @@ func (h *SSEHub) Run(ctx context.Context) {
 
 		case <-ctx.Done():
 			h.shutdown()
-			return
+			return nil
 		}
 	}
 }
 
-// IsSubscribed checks if client is subscribed to a topic
+// runClientDispatcher drains client's outbox one message at a time on its
+// own goroutine, started by Serve's register case and ending when Serve's
+// unregister case (or shutdown) closes the outbox. Funneling every message
+// for a given client through this single goroutine is what guarantees
+// delivery order matches broadcast order; Serve's broadcast case only ever
+// enqueues onto outbox, never calls deliver directly, so a client blocked
+// under OverflowBlock (or being torn down under OverflowDisconnect) can't
+// wedge delivery to every other client sharing the Serve loop. This
+// goroutine is also the only writer to client.Send, so it — not Serve's
+// unregister case — closes Send once outbox is drained, avoiding a close
+// racing a send still in flight.
+func (h *SSEHub) runClientDispatcher(client *Client) {
+	for message := range client.outbox {
+		h.deliver(client, message)
+	}
+	close(client.Send)
+}
+
+// deliver applies client's leaky-bucket backpressure to message before
+// attempting delivery.
+func (h *SSEHub) deliver(client *Client, message Message) {
+	accepted, disconnect := client.bucket.Pour(len(message.Data))
+	if disconnect {
+		h.unregisterClient(client)
+		return
+	}
+	if !accepted {
+		// Dropped: the bucket already counted it in Stats.
+		return
+	}
+
+	select {
+	case client.Send <- message:
+	default:
+		// The bucket accepted the pour but the Send buffer is still full,
+		// meaning the client's stream goroutine itself has stalled rather
+		// than just running behind on bytes. Disconnect as a last resort.
+		h.unregisterClient(client)
+	}
+}
+
+// ClientStats returns a leaky-bucket snapshot for every connected client,
+// keyed by client ID, for the ClientStats RPC to surface to operators.
+func (h *SSEHub) ClientStats() map[string]BucketStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make(map[string]BucketStats, len(h.clients))
+	for id, client := range h.clients {
+		stats[id] = client.bucket.Stats(len(client.Send))
+	}
+	return stats
+}
+
+// IsSubscribed checks if client is subscribed to topic, evaluating its
+// compiled matchers (see topicMatcher) so hierarchical wildcard
+// subscriptions like "files/*" or "files/#" match as well as exact names.
 func (c *Client) IsSubscribed(topic string) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	// If no topics specified, subscribe to all
-	if len(c.Topics) == 0 {
+	if len(c.matchers) == 0 {
 		return true
 	}
 
-	return c.Topics[topic]
+	for _, m := range c.matchers {
+		if m.matches(topic) {
+			return true
+		}
+	}
+	return false
 }
 
-// Subscribe subscribes client to a topic
+// Subscribe subscribes client to a topic pattern (an exact topic name or an
+// MQTT-style wildcard such as "files/*", "files/#", or "*" for everything).
 func (c *Client) Subscribe(topic string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.Topics[topic] {
+		return
+	}
 	c.Topics[topic] = true
+	c.matchers = append(c.matchers, compileTopicMatcher(topic))
 }
 
-// Unsubscribe unsubscribes client from a topic
+// Unsubscribe unsubscribes client from a topic pattern previously passed to
+// Subscribe.
 func (c *Client) Unsubscribe(topic string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if !c.Topics[topic] {
+		return
+	}
 	delete(c.Topics, topic)
+
+	matchers := make([]*topicMatcher, 0, len(c.matchers))
+	for _, m := range c.matchers {
+		if m.pattern != topic {
+			matchers = append(matchers, m)
+		}
+	}
+	c.matchers = matchers
 }
 
 // ClientCount returns the number of active clients
@@ -141,34 +415,58 @@ func (h *SSEHub) ClientCount() int {
 	return len(h.clients)
 }
 
+// GetClient returns the currently registered client with the given ID, so a
+// handler for an already-open connection (e.g. HTTPServer's /sse/subscribe
+// and /sse/unsubscribe routes) can change its subscriptions in place
+// without needing it to reconnect.
+func (h *SSEHub) GetClient(id string) (*Client, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client, ok := h.clients[id]
+	return client, ok
+}
+
 // unregisterClient unregisters a client (internal helper)
 func (h *SSEHub) unregisterClient(client *Client) {
+	metrics.SlowClientDisconnectsTotal.Inc()
 	h.unregister <- client
 }
 
-// shutdown gracefully shuts down the hub
+// shutdown gracefully shuts down the hub, broadcasting a final "shutdown"
+// SSE frame to every connected client (so they know to reconnect
+// elsewhere) before closing their channels.
 func (h *SSEHub) shutdown() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	for _, client := range h.clients {
-		close(client.Send)
+		select {
+		case client.Send <- Message{Event: "shutdown"}:
+		default:
+			// Buffer full; the client is already too far behind to matter.
+		}
+		// close(client.Send) is runClientDispatcher's job once it's done
+		// draining outbox, not ours: we're sending the shutdown frame
+		// directly to Send here (bypassing outbox) specifically so it
+		// jumps ahead of whatever's still queued, so closing Send
+		// ourselves would race that goroutine's later close of the same
+		// channel.
+		close(client.outbox)
 	}
 	h.clients = make(map[string]*Client)
+	h.clientIPs = make(map[string]int)
+	metrics.ClientsConnected.Set(0)
 }
 
-// generateClientID generates a unique client ID
+// generateClientID generates a client ID from 16 bytes of crypto/rand
+// entropy, hex-encoded. Falls back to a timestamp-derived ID in the
+// extremely unlikely case the system CSPRNG is unavailable, so a client
+// still gets an ID rather than the connection failing outright.
 func generateClientID() string {
-	return time.Now().Format("20060102150405") + "-" + randomString(8)
-}
-
-// randomString generates a random string
-func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("fallback-%d", time.Now().UnixNano())
 	}
-	return string(b)
+	return hex.EncodeToString(buf)
 }
 
@@ -0,0 +1,49 @@
+package hub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestServeBroadcastsShutdownFrameOnContextCancel exercises the same signal
+// the shutdown coordinator in cmd/server/main.go relies on: cancelling the
+// hub's context must deliver a final "shutdown" frame to every connected
+// client before their Send channel is closed, and it must happen well
+// within the service's shutdown deadline.
+func TestServeBroadcastsShutdownFrameOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := NewSSEHub()
+	go h.Serve(ctx)
+
+	client := h.NewClient(ctx)
+	if err := h.Register(client, "127.0.0.1"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	// Give the register event a moment to be processed before shutting down.
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+
+	select {
+	case msg, ok := <-client.Send:
+		if !ok {
+			t.Fatal("client.Send closed before delivering the shutdown frame")
+		}
+		if msg.Event != "shutdown" {
+			t.Fatalf("got event %q, want %q", msg.Event, "shutdown")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for shutdown frame")
+	}
+
+	select {
+	case _, ok := <-client.Send:
+		if ok {
+			t.Fatal("expected client.Send to be closed after the shutdown frame")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for client.Send to close")
+	}
+}
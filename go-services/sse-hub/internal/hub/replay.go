@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"sort"
+	"sync"
+)
+
+// defaultReplayBufferSize is how many messages a topicRingBuffer retains
+// when SetReplayBufferSize hasn't been called to override it.
+const defaultReplayBufferSize = 256
+
+// topicRingBuffer is a fixed-capacity ring buffer of the most recently
+// broadcast messages on one topic, used to replay messages a client missed
+// while disconnected.
+type topicRingBuffer struct {
+	mu       sync.RWMutex
+	messages []Message
+	next     int
+	size     int
+}
+
+func newTopicRingBuffer(capacity int) *topicRingBuffer {
+	return &topicRingBuffer{messages: make([]Message, capacity)}
+}
+
+// add records msg, overwriting the oldest buffered message once the ring is
+// full.
+func (b *topicRingBuffer) add(msg Message) {
+	capacity := len(b.messages)
+	if capacity == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.messages[b.next] = msg
+	b.next = (b.next + 1) % capacity
+	if b.size < capacity {
+		b.size++
+	}
+}
+
+// since returns every buffered message with Seq > afterSeq, oldest first.
+func (b *topicRingBuffer) since(afterSeq uint64) []Message {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	capacity := len(b.messages)
+	if capacity == 0 {
+		return nil
+	}
+
+	var out []Message
+	start := (b.next - b.size + capacity) % capacity
+	for i := 0; i < b.size; i++ {
+		msg := b.messages[(start+i)%capacity]
+		if msg.Seq > afterSeq {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// replayStore keeps one topicRingBuffer per topic, so a client resuming
+// with Last-Event-ID can replay only the topics it's subscribed to.
+type replayStore struct {
+	mu       sync.RWMutex
+	capacity int
+	buffers  map[string]*topicRingBuffer
+}
+
+func newReplayStore(capacity int) *replayStore {
+	if capacity <= 0 {
+		capacity = defaultReplayBufferSize
+	}
+	return &replayStore{
+		capacity: capacity,
+		buffers:  make(map[string]*topicRingBuffer),
+	}
+}
+
+// setCapacity changes the capacity of buffers created from this point
+// forward; existing buffers keep their current capacity.
+func (s *replayStore) setCapacity(capacity int) {
+	if capacity <= 0 {
+		capacity = defaultReplayBufferSize
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capacity = capacity
+}
+
+func (s *replayStore) record(msg Message) {
+	s.mu.Lock()
+	buf, ok := s.buffers[msg.Topic]
+	if !ok {
+		buf = newTopicRingBuffer(s.capacity)
+		s.buffers[msg.Topic] = buf
+	}
+	s.mu.Unlock()
+
+	buf.add(msg)
+}
+
+// since returns, oldest first, every buffered message with Seq > afterSeq
+// whose topic satisfies match — typically a Client's IsSubscribed, so
+// wildcard subscriptions (e.g. "files/#") replay every matching topic's
+// buffer, not just one with an exactly equal name.
+func (s *replayStore) since(afterSeq uint64, match func(topic string) bool) []Message {
+	s.mu.RLock()
+	bufs := make([]*topicRingBuffer, 0, len(s.buffers))
+	for topic, buf := range s.buffers {
+		if match(topic) {
+			bufs = append(bufs, buf)
+		}
+	}
+	s.mu.RUnlock()
+
+	var out []Message
+	for _, buf := range bufs {
+		out = append(out, buf.since(afterSeq)...)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Seq < out[j].Seq })
+	return out
+}
@@ -0,0 +1,363 @@
+package hub
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Backoff and dedup tuning for the replication mesh.
+const (
+	replicationMinBackoff = 500 * time.Millisecond
+	replicationMaxBackoff = 30 * time.Second
+
+	// seenTTL bounds how long a (OriginNode, Seq) pair is remembered for
+	// dedup purposes; seenGCThreshold is how large the dedup map is allowed
+	// to grow before a sweep evicts entries older than seenTTL.
+	seenTTL          = 5 * time.Minute
+	seenGCThreshold  = 10000
+	defaultOutboxLen = 256
+)
+
+// ReplicationConfig configures cross-instance replication for an SSEHub:
+// this node's ID, the address peers dial to reach it, the set of peer
+// addresses to dial out to, the mutual-TLS material both sides
+// authenticate with, and how deep each peer's outbound queue may grow
+// before messages are dropped.
+type ReplicationConfig struct {
+	NodeID     string
+	ListenAddr string
+	Peers      []string
+	CertFile   string
+	KeyFile    string
+	CAFile     string
+	OutboxSize int
+}
+
+// replicationMessage is the wire format exchanged between peers: one JSON
+// object per line over a persistent TLS connection.
+type replicationMessage struct {
+	OriginNode string `json:"origin_node"`
+	Seq        uint64 `json:"seq"`
+	Event      string `json:"event"`
+	Topic      string `json:"topic"`
+	Data       string `json:"data"`
+}
+
+// PeerStatus is a point-in-time snapshot of one outbound replication
+// connection, surfaced by the ReplicationStatus RPC for observability.
+type PeerStatus struct {
+	Address    string
+	Connected  bool
+	QueueDepth int
+}
+
+// Replicator fans a hub's locally-published messages out to a fleet of peer
+// instances and merges messages published by those peers back into the
+// local hub, so a subscriber connected to any one node still sees every
+// message published fleet-wide. It's modeled as a full mesh: every node
+// dials every other configured peer directly, each over its own
+// replicationSender, rather than forwarding messages onward hop by hop.
+type Replicator struct {
+	hub    *SSEHub
+	cfg    ReplicationConfig
+	tlsCfg *tls.Config
+
+	mu      sync.RWMutex
+	senders map[string]*replicationSender
+
+	seenMu sync.Mutex
+	seen   map[string]time.Time
+}
+
+// NewReplicator builds a Replicator for h from cfg. It loads the mutual TLS
+// material up front so a misconfigured cert/key/CA fails fast at startup
+// instead of on the first peer connection attempt.
+func NewReplicator(h *SSEHub, cfg ReplicationConfig) (*Replicator, error) {
+	tlsCfg, err := loadMutualTLSConfig(cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("replication: %w", err)
+	}
+	if cfg.OutboxSize <= 0 {
+		cfg.OutboxSize = defaultOutboxLen
+	}
+
+	return &Replicator{
+		hub:     h,
+		cfg:     cfg,
+		tlsCfg:  tlsCfg,
+		senders: make(map[string]*replicationSender),
+		seen:    make(map[string]time.Time),
+	}, nil
+}
+
+// Start launches the replicationListener accepting inbound peer connections
+// on cfg.ListenAddr and one replicationSender goroutine per configured
+// peer. It returns once the listener is up; senders dial their peers in
+// the background and keep retrying with backoff, and the listener keeps
+// accepting, until ctx is cancelled.
+func (r *Replicator) Start(ctx context.Context) error {
+	lis, err := tls.Listen("tcp", r.cfg.ListenAddr, r.tlsCfg)
+	if err != nil {
+		return fmt.Errorf("replication: listen on %s: %w", r.cfg.ListenAddr, err)
+	}
+
+	go r.acceptLoop(ctx, lis)
+	go func() {
+		<-ctx.Done()
+		lis.Close()
+	}()
+
+	for _, addr := range r.cfg.Peers {
+		s := newReplicationSender(addr, r.tlsCfg, r.cfg.OutboxSize)
+		r.mu.Lock()
+		r.senders[addr] = s
+		r.mu.Unlock()
+		go s.run(ctx)
+	}
+
+	return nil
+}
+
+// Status returns a PeerStatus for every configured peer.
+func (r *Replicator) Status() []PeerStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]PeerStatus, 0, len(r.senders))
+	for _, s := range r.senders {
+		statuses = append(statuses, s.status())
+	}
+	return statuses
+}
+
+// enqueue fans msg out to every peer sender's outbox.
+func (r *Replicator) enqueue(msg Message) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.senders {
+		s.enqueue(msg)
+	}
+}
+
+// acceptLoop accepts inbound peer connections and hands each to
+// handlePeerConn on its own goroutine until ctx is cancelled.
+func (r *Replicator) acceptLoop(ctx context.Context, lis net.Listener) {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("replication: accept error: %v", err)
+			continue
+		}
+		go r.handlePeerConn(conn)
+	}
+}
+
+// handlePeerConn reads newline-delimited JSON replicationMessages off conn
+// and merges each into the local hub until the connection closes.
+func (r *Replicator) handlePeerConn(conn net.Conn) {
+	defer conn.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var wm replicationMessage
+		if err := dec.Decode(&wm); err != nil {
+			return
+		}
+		r.merge(Message{
+			Event:      wm.Event,
+			Topic:      wm.Topic,
+			Data:       wm.Data,
+			OriginNode: wm.OriginNode,
+			Seq:        wm.Seq,
+		})
+	}
+}
+
+// merge delivers a message received from a peer to local clients exactly
+// once: a duplicate (OriginNode, Seq) pair, from a peer reconnect or the
+// same message arriving over more than one connection, is dropped instead
+// of being re-broadcast. It doesn't re-enqueue to other peers — the full
+// mesh means every node already has its own direct connection to every
+// other peer, so there's nothing further to forward.
+func (r *Replicator) merge(msg Message) {
+	key := msg.OriginNode + "/" + strconv.FormatUint(msg.Seq, 10)
+
+	r.seenMu.Lock()
+	if _, dup := r.seen[key]; dup {
+		r.seenMu.Unlock()
+		return
+	}
+	r.seen[key] = time.Now()
+	r.gcSeenLocked()
+	r.seenMu.Unlock()
+
+	r.hub.push(msg)
+}
+
+// gcSeenLocked evicts dedup entries older than seenTTL once the dedup map
+// has grown past seenGCThreshold. Called with seenMu held.
+func (r *Replicator) gcSeenLocked() {
+	if len(r.seen) < seenGCThreshold {
+		return
+	}
+	cutoff := time.Now().Add(-seenTTL)
+	for k, t := range r.seen {
+		if t.Before(cutoff) {
+			delete(r.seen, k)
+		}
+	}
+}
+
+// replicationSender owns a single outbound, TLS-authenticated connection to
+// one peer, redialing with exponential backoff whenever the connection
+// drops or can't be established. Messages enqueued while disconnected are
+// buffered in outbox up to its capacity; once full, further sends are
+// dropped so a slow or unreachable peer can't backpressure the rest of the
+// fleet.
+type replicationSender struct {
+	addr   string
+	tlsCfg *tls.Config
+	outbox chan Message
+
+	mu        sync.Mutex
+	connected bool
+}
+
+func newReplicationSender(addr string, tlsCfg *tls.Config, outboxSize int) *replicationSender {
+	return &replicationSender{
+		addr:   addr,
+		tlsCfg: tlsCfg,
+		outbox: make(chan Message, outboxSize),
+	}
+}
+
+// enqueue adds msg to the outbox, dropping it if the outbox is full.
+func (s *replicationSender) enqueue(msg Message) {
+	select {
+	case s.outbox <- msg:
+	default:
+		log.Printf("replication: outbox full for peer %s, dropping message", s.addr)
+	}
+}
+
+func (s *replicationSender) status() PeerStatus {
+	s.mu.Lock()
+	connected := s.connected
+	s.mu.Unlock()
+	return PeerStatus{Address: s.addr, Connected: connected, QueueDepth: len(s.outbox)}
+}
+
+func (s *replicationSender) setConnected(connected bool) {
+	s.mu.Lock()
+	s.connected = connected
+	s.mu.Unlock()
+}
+
+// run dials addr and streams outbox messages to it until ctx is cancelled,
+// reconnecting with exponential backoff (capped at replicationMaxBackoff)
+// whenever the connection drops or can't be established.
+func (s *replicationSender) run(ctx context.Context) {
+	backoff := replicationMinBackoff
+	for ctx.Err() == nil {
+		conn, err := tls.Dial("tcp", s.addr, s.tlsCfg)
+		if err != nil {
+			log.Printf("replication: dial %s failed: %v", s.addr, err)
+			if !sleepOrDone(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+
+		s.setConnected(true)
+		backoff = replicationMinBackoff
+		s.stream(ctx, conn)
+		s.setConnected(false)
+	}
+}
+
+// stream writes outbox messages to conn as newline-delimited JSON until
+// ctx is cancelled or a write fails, at which point run redials.
+func (s *replicationSender) stream(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	enc := json.NewEncoder(conn)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-s.outbox:
+			wm := replicationMessage{
+				OriginNode: msg.OriginNode,
+				Seq:        msg.Seq,
+				Event:      msg.Event,
+				Topic:      msg.Topic,
+				Data:       msg.Data,
+			}
+			if err := enc.Encode(&wm); err != nil {
+				log.Printf("replication: send to %s failed: %v", s.addr, err)
+				return
+			}
+		}
+	}
+}
+
+// nextBackoff doubles d, capped at replicationMaxBackoff.
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > replicationMaxBackoff {
+		d = replicationMaxBackoff
+	}
+	return d
+}
+
+// sleepOrDone waits for d to elapse, returning false early if ctx is
+// cancelled first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// loadMutualTLSConfig builds a tls.Config that presents certFile/keyFile as
+// this node's identity and both trusts and requires peer certificates
+// signed by caFile, since every node in the mesh acts as both a TLS client
+// (replicationSender) and server (replicationListener).
+func loadMutualTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load cert/key: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("load CA: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      caPool,
+		ClientCAs:    caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}, nil
+}
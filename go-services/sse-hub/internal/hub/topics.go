@@ -0,0 +1,41 @@
+package hub
+
+import "strings"
+
+// topicMatcher is a compiled subscription pattern using MQTT-style
+// hierarchical wildcards over "/"-separated segments: "*" matches exactly
+// one segment, and "#" as a segment matches that segment and everything
+// after it. The bare pattern "*" is a special case meaning "every topic",
+// distinct from "*" as a single segment (which only matches single-segment
+// topics).
+type topicMatcher struct {
+	pattern  string
+	segments []string
+}
+
+// compileTopicMatcher compiles pattern into a topicMatcher ready for
+// repeated matches calls.
+func compileTopicMatcher(pattern string) *topicMatcher {
+	return &topicMatcher{pattern: pattern, segments: strings.Split(pattern, "/")}
+}
+
+// matches reports whether topic satisfies this pattern.
+func (m *topicMatcher) matches(topic string) bool {
+	if m.pattern == "*" {
+		return true
+	}
+
+	topicSegments := strings.Split(topic, "/")
+	for i, seg := range m.segments {
+		if seg == "#" {
+			return true
+		}
+		if i >= len(topicSegments) {
+			return false
+		}
+		if seg != "*" && seg != topicSegments[i] {
+			return false
+		}
+	}
+	return len(m.segments) == len(topicSegments)
+}
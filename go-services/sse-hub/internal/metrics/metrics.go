@@ -0,0 +1,36 @@
+// Package metrics defines the Prometheus metrics the SSE Hub exposes on its
+// /metrics endpoint, for operational tuning of per-client bucket sizes and
+// replay buffer depth.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ClientsConnected tracks how many SSE clients are currently
+	// registered with the hub.
+	ClientsConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "obby_sse_clients",
+		Help: "Currently connected SSE clients.",
+	})
+
+	// MessagesBroadcastTotal counts messages fanned out to local clients.
+	// This is intentionally not broken down by topic: topics come from the
+	// caller-supplied Publish RPC field with no fixed vocabulary, and a
+	// per-topic label would let a client mint unbounded Prometheus series
+	// just by publishing to novel topics.
+	MessagesBroadcastTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "obby_sse_messages_broadcast_total",
+		Help: "Messages broadcast to local clients.",
+	})
+
+	// SlowClientDisconnectsTotal counts clients forcibly disconnected
+	// because they couldn't keep up with their leaky bucket or Send
+	// buffer.
+	SlowClientDisconnectsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "obby_sse_slow_client_disconnects_total",
+		Help: "Clients disconnected for falling behind on delivery.",
+	})
+)
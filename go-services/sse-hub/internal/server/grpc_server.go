@@ -2,15 +2,18 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net"
-	"os"
-	"os/signal"
-	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	log "github.com/sirupsen/logrus"
 
 	pb "github.com/obby/sse-hub/proto/generated"
 	"github.com/obby/sse-hub/internal/hub"
@@ -57,16 +60,23 @@ func (s *SSEServiceServer) Publish(ctx context.Context, req *pb.PublishRequest)
 func (s *SSEServiceServer) RegisterClient(req *pb.ClientRequest, stream pb.SSEService_RegisterClientServer) error {
 	log.Printf("RegisterClient called - Topics: %v", req.GetTopics())
 
-	// Create new client
-	client := s.hub.NewClient(stream.Context())
+	// Create new client, applying any per-client bucket overrides the
+	// caller requested on top of the hub's defaults.
+	client := s.hub.NewClientWithBucket(stream.Context(), bucketConfigFromRequest(req))
 
 	// Subscribe to requested topics
 	for _, topic := range req.GetTopics() {
 		client.Subscribe(topic)
 	}
 
-	// Register client with hub
-	s.hub.Register(client)
+	// Register client with hub, enforcing the same per-IP connection cap
+	// as the HTTP transport.
+	if err := s.hub.Register(client, peerAddr(stream.Context())); err != nil {
+		if errors.Is(err, hub.ErrTooManyConnections) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return err
+	}
 
 	// Set up cancellation for when stream is closed
 	ctx := stream.Context()
@@ -95,30 +105,139 @@ func (s *SSEServiceServer) RegisterClient(req *pb.ClientRequest, stream pb.SSESe
 	}
 }
 
-// StartGRPCServer starts the gRPC server
-func StartGRPCServer(port int, hub *hub.SSEHub) error {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+// ReplicationStatus implements the ReplicationStatus RPC, reporting each
+// configured replication peer's connection state and outbox depth for
+// observability. It returns an empty peer list if replication hasn't been
+// enabled on this node.
+func (s *SSEServiceServer) ReplicationStatus(ctx context.Context, req *pb.ReplicationStatusRequest) (*pb.ReplicationStatusResponse, error) {
+	resp := &pb.ReplicationStatusResponse{}
+	for _, peer := range s.hub.ReplicationPeerStatus() {
+		resp.Peers = append(resp.Peers, &pb.PeerStatus{
+			Address:    peer.Address,
+			Connected:  peer.Connected,
+			QueueDepth: int32(peer.QueueDepth),
+		})
+	}
+	return resp, nil
+}
+
+// ClientStats implements the ClientStats RPC, reporting each connected
+// client's leaky-bucket queue depth (bytes and messages), dropped-message
+// count, and last-drain time so operators can spot slow consumers.
+func (s *SSEServiceServer) ClientStats(ctx context.Context, req *pb.ClientStatsRequest) (*pb.ClientStatsResponse, error) {
+	resp := &pb.ClientStatsResponse{}
+	for id, stats := range s.hub.ClientStats() {
+		resp.Clients = append(resp.Clients, &pb.ClientBucketStats{
+			ClientId:        id,
+			QueueBytes:      stats.QueueBytes,
+			QueueMessages:   int32(stats.QueueMessages),
+			Dropped:         stats.Dropped,
+			LastDrainUnixMs: stats.LastDrain.UnixMilli(),
+		})
+	}
+	return resp, nil
+}
+
+// bucketConfigFromRequest builds a hub.BucketConfig for a RegisterClient
+// call, starting from the hub's defaults and applying whichever overrides
+// the caller set (a zero value means "use the default").
+func bucketConfigFromRequest(req *pb.ClientRequest) hub.BucketConfig {
+	cfg := hub.DefaultBucketConfig()
+
+	if v := req.GetBucketCapacityBytes(); v > 0 {
+		cfg.CapacityBytes = v
+	}
+	if v := req.GetBucketCapacityMessages(); v > 0 {
+		cfg.CapacityMessages = int(v)
+	}
+	if v := req.GetDrainRateBps(); v > 0 {
+		cfg.DrainRate = v
+	}
+	if v := req.GetBlockDeadlineMs(); v > 0 {
+		cfg.BlockDeadline = time.Duration(v) * time.Millisecond
+	}
+
+	switch req.GetOverflowPolicy() {
+	case "block":
+		cfg.Policy = hub.OverflowBlock
+	case "disconnect":
+		cfg.Policy = hub.OverflowDisconnect
+	case "drop", "":
+		cfg.Policy = hub.OverflowDrop
+	}
+
+	return cfg
+}
+
+// peerAddr extracts the connecting client's IP from ctx's gRPC peer info,
+// falling back to an empty string (which Register treats as "no cap") if
+// it's unavailable, or to the raw address if it isn't a "host:port" pair.
+func peerAddr(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
 	if err != nil {
-		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+		return p.Addr.String()
 	}
+	return host
+}
 
+// NewGRPCServer constructs the gRPC server and registers the SSE service
+// on it, without starting to serve. The caller owns the returned
+// *grpc.Server and is responsible for serving and stopping it, either via
+// GRPCService (as cmd/server/main.go does) or via StartGRPCServer and
+// GracefulStop.
+func NewGRPCServer(hub *hub.SSEHub) *grpc.Server {
 	s := grpc.NewServer()
 	pb.RegisterSSEServiceServer(s, NewSSEServiceServer(hub))
 
 	// Enable reflection for development
 	reflection.Register(s)
 
+	return s
+}
+
+// StartGRPCServer listens on port and serves s. It blocks until the
+// listener is closed (typically via s.GracefulStop()) or fails.
+func StartGRPCServer(s *grpc.Server, port int) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+
 	log.Printf("SSE Hub gRPC server starting on port %d", port)
+	return s.Serve(lis)
+}
 
-	// Graceful shutdown
-	go func() {
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
+// GRPCService adapts a *grpc.Server to supervisor.Service: Serve starts
+// listening on port and blocks until ctx is canceled or the server fails
+// on its own, gracefully stopping the server in the former case.
+type GRPCService struct {
+	Server *grpc.Server
+	Port   int
+}
+
+// Serve implements supervisor.Service for GRPCService.
+func (g *GRPCService) Serve(ctx context.Context) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", g.Port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", g.Port, err)
+	}
 
-		log.Println("Shutting down SSE Hub gRPC server...")
-		s.GracefulStop()
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("SSE Hub gRPC server starting on port %d", g.Port)
+		errCh <- g.Server.Serve(lis)
 	}()
 
-	return s.Serve(lis)
+	select {
+	case <-ctx.Done():
+		g.Server.GracefulStop()
+		<-errCh
+		return nil
+	case err := <-errCh:
+		return err
+	}
 }
\ No newline at end of file
@@ -4,26 +4,46 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+
 	"github.com/obby/sse-hub/internal/hub"
 )
 
+// defaultShutdownTimeout bounds how long Serve waits for in-flight SSE
+// connections to drain after ctx is canceled, when SetShutdownTimeout
+// hasn't been called to override it.
+const defaultShutdownTimeout = 30 * time.Second
+
+// defaultConnectRate and defaultConnectBurst govern the per-IP connection
+// rate limiter when SetConnectRateLimit hasn't been called to override
+// them: two new connections per second, bursting up to five.
+const (
+	defaultConnectRate  = 2.0
+	defaultConnectBurst = 5
+)
+
 // HTTPServer handles HTTP SSE connections from the frontend
 type HTTPServer struct {
-	hub   *hub.SSEHub
-	mux   *http.ServeMux
-	server *http.Server
+	hub             *hub.SSEHub
+	mux             *http.ServeMux
+	server          *http.Server
+	shutdownTimeout time.Duration
+	connectLimiter  *ipRateLimiter
 }
 
 // NewHTTPServer creates a new HTTP SSE server
 func NewHTTPServer(hub *hub.SSEHub, port int) *HTTPServer {
 	mux := http.NewServeMux()
 	h := &HTTPServer{
-		hub:   hub,
-		mux:   mux,
+		hub: hub,
+		mux: mux,
 		server: &http.Server{
 			Addr:         fmt.Sprintf(":%d", port),
 			Handler:      mux,
@@ -31,17 +51,34 @@ func NewHTTPServer(hub *hub.SSEHub, port int) *HTTPServer {
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  120 * time.Second,
 		},
+		shutdownTimeout: defaultShutdownTimeout,
+		connectLimiter:  newIPRateLimiter(defaultConnectRate, defaultConnectBurst),
 	}
 
 	// Register routes
 	mux.HandleFunc("/sse", h.handleSSE)
+	mux.HandleFunc("/sse/subscribe", h.handleSubscribe)
+	mux.HandleFunc("/sse/unsubscribe", h.handleUnsubscribe)
 	mux.HandleFunc("/health", h.handleHealth)
+	mux.Handle("/metrics", promhttp.Handler())
 
 	return h
 }
 
+// SetConnectRateLimit overrides the per-IP SSE connection rate limit: rate
+// new connections per second, bursting up to burst.
+func (h *HTTPServer) SetConnectRateLimit(rate float64, burst int) {
+	h.connectLimiter = newIPRateLimiter(rate, burst)
+}
+
 // handleSSE handles Server-Sent Events connection
 func (h *HTTPServer) handleSSE(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r)
+	if !h.connectLimiter.Allow(ip) {
+		http.Error(w, "Too many connection attempts", http.StatusTooManyRequests)
+		return
+	}
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -61,14 +98,17 @@ func (h *HTTPServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Register client
-	h.hub.Register(client)
+	if err := h.hub.Register(client, ip); err != nil {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
 
-	log.Printf("New SSE connection - Client: %s, Topics: %v", client.ID, topics)
+	log.WithFields(log.Fields{"client": client.ID, "topics": topics}).Info("new SSE connection")
 
 	// Set up connection close detection
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		log.Printf("Connection does not support flushing")
+		log.Error("connection does not support flushing")
 		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
 		return
 	}
@@ -77,25 +117,38 @@ func (h *HTTPServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "event: connected\ndata: %s\n\n", client.ID)
 	flusher.Flush()
 
+	// Resume: if the client disconnected and is reconnecting with the last
+	// event ID it saw (standard SSE "Last-Event-ID" header, or a
+	// "lastEventId" query param for clients that can't set headers, e.g.
+	// EventSource from a browser), replay everything it missed before
+	// switching to live streaming.
+	if lastEventID, ok := h.lastEventIDFromRequest(r); ok {
+		for _, msg := range h.hub.ReplaySince(client, lastEventID) {
+			if err := writeSSEMessage(w, msg); err != nil {
+				log.WithError(err).WithField("client", client.ID).Error("error writing replayed SSE message")
+				h.hub.Unregister(client)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+
 	// Keep connection alive and send messages
 	pingTicker := time.NewTicker(30 * time.Second)
 	defer pingTicker.Stop()
 
 	for {
 		select {
-		case msg := <-client.Send:
-			// Send SSE message
-			data, err := json.Marshal(map[string]string{
-				"event": msg.Event,
-				"topic": msg.Topic,
-				"data":  msg.Data,
-			})
-			if err != nil {
-				log.Printf("Error marshaling SSE message: %v", err)
-				continue
+		case msg, ok := <-client.Send:
+			if !ok {
+				// Hub closed our channel (shutdown or forced disconnect).
+				return
 			}
 
-			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, string(data))
+			if err := writeSSEMessage(w, msg); err != nil {
+				log.WithError(err).WithField("client", client.ID).Error("error writing SSE message")
+				continue
+			}
 			flusher.Flush()
 
 		case <-pingTicker.C:
@@ -105,7 +158,7 @@ func (h *HTTPServer) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 		case <-r.Context().Done():
 			// Client disconnected
-			log.Printf("SSE client disconnected: %s", client.ID)
+			log.WithField("client", client.ID).Info("SSE client disconnected")
 			h.hub.Unregister(client)
 			return
 		}
@@ -124,11 +177,61 @@ func (h *HTTPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// getTopicsFromRequest extracts topics from HTTP request
+// writeSSEMessage writes msg to w as one SSE frame, tagging it with an
+// "id:" line carrying its Seq so a reconnecting client can send it back as
+// Last-Event-ID.
+func writeSSEMessage(w http.ResponseWriter, msg hub.Message) error {
+	data, err := json.Marshal(map[string]string{
+		"event": msg.Event,
+		"topic": msg.Topic,
+		"data":  msg.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal SSE message: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.Seq, msg.Event, string(data))
+	return err
+}
+
+// lastEventIDFromRequest extracts the sequence ID a reconnecting client
+// last saw, from the standard "Last-Event-ID" header or a "lastEventId"
+// query param fallback, reporting false if neither is present or valid.
+func (h *HTTPServer) lastEventIDFromRequest(r *http.Request) (uint64, bool) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("lastEventId")
+	}
+	if raw == "" {
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		log.WithError(err).WithField("last_event_id", raw).Warn("ignoring invalid Last-Event-ID")
+		return 0, false
+	}
+	return id, true
+}
+
+// clientIP extracts the connecting client's IP from r.RemoteAddr, falling
+// back to the raw value if it isn't a "host:port" pair (e.g. in tests).
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// getTopicsFromRequest extracts topics from HTTP request: a comma-separated
+// "topics" query param, a JSON array or comma-separated string body, each
+// entry an exact topic name or an MQTT-style wildcard pattern (see
+// topicMatcher).
 func (h *HTTPServer) getTopicsFromRequest(r *http.Request) []string {
 	// Try query parameters first
 	if topicsParam := r.URL.Query().Get("topics"); topicsParam != "" {
-		return []string{topicsParam}
+		return splitTopics(topicsParam)
 	}
 
 	// Try request body
@@ -136,16 +239,18 @@ func (h *HTTPServer) getTopicsFromRequest(r *http.Request) []string {
 		var body map[string]interface{}
 		if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
 			if topics, ok := body["topics"].([]interface{}); ok {
-				topicsStr := make([]string, len(topics))
-				for i, topic := range topics {
+				topicsStr := make([]string, 0, len(topics))
+				for _, topic := range topics {
 					if str, ok := topic.(string); ok {
-						topicsStr[i] = str
+						topicsStr = append(topicsStr, str)
 					}
 				}
-				return topicsStr
+				if len(topicsStr) > 0 {
+					return topicsStr
+				}
 			}
 			if topic, ok := body["topic"].(string); ok {
-				return []string{topic}
+				return splitTopics(topic)
 			}
 		}
 	}
@@ -154,21 +259,88 @@ func (h *HTTPServer) getTopicsFromRequest(r *http.Request) []string {
 	return []string{"*"}
 }
 
-// Start starts the HTTP server
-func (h *HTTPServer) Start() error {
-	log.Printf("SSE Hub HTTP server starting on port %s", h.server.Addr)
+// splitTopics splits a comma-separated list of topic patterns, trimming
+// whitespace and dropping empty entries.
+func splitTopics(raw string) []string {
+	parts := strings.Split(raw, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
 
-	// Start hub in background
-	hubCtx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	go h.hub.Run(hubCtx)
+// handleSubscribe adds one or more topic patterns to an already-connected
+// client's subscriptions, letting a long-lived SSE connection change what
+// it receives without reconnecting. Expects POST /sse/subscribe?client=ID&topic=pattern[,pattern...].
+func (h *HTTPServer) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	h.handleTopicChange(w, r, true)
+}
 
-	// Start HTTP server
-	return h.server.ListenAndServe()
+// handleUnsubscribe is handleSubscribe's counterpart: POST
+// /sse/unsubscribe?client=ID&topic=pattern[,pattern...].
+func (h *HTTPServer) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	h.handleTopicChange(w, r, false)
 }
 
-// Stop stops the HTTP server gracefully
-func (h *HTTPServer) Stop(ctx context.Context) error {
-	log.Printf("Shutting down SSE Hub HTTP server...")
-	return h.server.Shutdown(ctx)
+func (h *HTTPServer) handleTopicChange(w http.ResponseWriter, r *http.Request, subscribe bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client")
+	topics := splitTopics(r.URL.Query().Get("topic"))
+	if clientID == "" || len(topics) == 0 {
+		http.Error(w, "client and topic are required", http.StatusBadRequest)
+		return
+	}
+
+	client, ok := h.hub.GetClient(clientID)
+	if !ok {
+		http.Error(w, "unknown client", http.StatusNotFound)
+		return
+	}
+
+	for _, topic := range topics {
+		if subscribe {
+			client.Subscribe(topic)
+		} else {
+			client.Unsubscribe(topic)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetShutdownTimeout overrides how long Serve waits for in-flight SSE
+// connections to drain after ctx is canceled.
+func (h *HTTPServer) SetShutdownTimeout(d time.Duration) {
+	h.shutdownTimeout = d
+}
+
+// Serve starts the HTTP server and blocks until ctx is canceled or the
+// server fails on its own, implementing supervisor.Service. On
+// cancellation it gracefully shuts the server down (bounded by
+// shutdownTimeout), letting in-flight SSE connections drain.
+func (h *HTTPServer) Serve(ctx context.Context) error {
+	log.WithField("addr", h.server.Addr).Info("SSE Hub HTTP server starting")
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- h.server.ListenAndServe() }()
+
+	select {
+	case <-ctx.Done():
+		log.Info("shutting down SSE Hub HTTP server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), h.shutdownTimeout)
+		defer cancel()
+		return h.server.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		if err == http.ErrServerClosed {
+			return nil
+		}
+		return err
+	}
 }
\ No newline at end of file
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// ipBucketIdleTTL bounds how long an IP's bucket is remembered after its
+	// last request; ipRateLimiterGCThreshold is how large the bucket map is
+	// allowed to grow before a sweep evicts entries idle past ipBucketIdleTTL.
+	ipBucketIdleTTL          = 10 * time.Minute
+	ipRateLimiterGCThreshold = 10000
+)
+
+// ipBucket is a per-IP token bucket: it holds up to burst tokens, refilling
+// at rate tokens/sec, and Allow consumes one token per connection attempt.
+type ipBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// ipRateLimiter rate-limits new connection attempts per source IP — a
+// separate, coarser control from the per-client leaky bucket in
+// hub.BucketConfig, which governs an already-accepted client's byte
+// throughput rather than how fast new connections may be opened.
+type ipRateLimiter struct {
+	rate  float64
+	burst int
+
+	mu      sync.Mutex
+	buckets map[string]*ipBucket
+}
+
+// newIPRateLimiter creates a limiter allowing rate connection attempts per
+// second per IP, with bursts up to burst.
+func newIPRateLimiter(rate float64, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*ipBucket),
+	}
+}
+
+// Allow reports whether ip may open another connection right now, consuming
+// one token from its bucket if so.
+func (l *ipRateLimiter) Allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &ipBucket{tokens: float64(l.burst), lastRefill: now}
+		l.buckets[ip] = b
+	}
+
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.tokens += elapsed * l.rate
+	if max := float64(l.burst); b.tokens > max {
+		b.tokens = max
+	}
+	b.lastRefill = now
+
+	l.gcLocked(now)
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// gcLocked evicts buckets idle past ipBucketIdleTTL once the bucket map has
+// grown past ipRateLimiterGCThreshold. Called with mu held.
+func (l *ipRateLimiter) gcLocked(now time.Time) {
+	if len(l.buckets) < ipRateLimiterGCThreshold {
+		return
+	}
+	cutoff := now.Add(-ipBucketIdleTTL)
+	for ip, b := range l.buckets {
+		if b.lastRefill.Before(cutoff) {
+			delete(l.buckets, ip)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package supervisor runs a fixed set of long-running services, each
+// exposing a Suture-style Serve(ctx) error method, restarting any that
+// crash with exponential backoff and returning once every service has
+// exited after its context is canceled.
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Service is a long-running component a Supervisor can run. Serve should
+// block until ctx is canceled, performing its own graceful shutdown before
+// returning; any other return (nil or non-nil) is treated as a crash and
+// triggers a restart.
+type Service interface {
+	Serve(ctx context.Context) error
+}
+
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// Supervisor runs a fixed set of named Services, each in its own
+// goroutine, restarting any that crash (return before ctx is canceled)
+// with exponential backoff between attempts.
+type Supervisor struct {
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu       sync.Mutex
+	services []namedService
+}
+
+type namedService struct {
+	name string
+	svc  Service
+}
+
+// New creates a Supervisor with default backoff bounds (100ms, doubling up
+// to 30s).
+func New() *Supervisor {
+	return &Supervisor{
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+}
+
+// Add registers a service under name (used only in restart log lines) to
+// run once Serve is called. Add must be called before Serve.
+func (s *Supervisor) Add(name string, svc Service) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.services = append(s.services, namedService{name: name, svc: svc})
+}
+
+// Serve runs every registered service concurrently and blocks until ctx is
+// canceled and every service has returned from its final Serve call.
+func (s *Supervisor) Serve(ctx context.Context) error {
+	s.mu.Lock()
+	services := append([]namedService(nil), s.services...)
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(len(services))
+	for _, ns := range services {
+		go func(ns namedService) {
+			defer wg.Done()
+			s.runWithRestart(ctx, ns)
+		}(ns)
+	}
+	wg.Wait()
+	return nil
+}
+
+// runWithRestart runs ns.svc.Serve until ctx is canceled, restarting it
+// with exponential backoff each time it returns early (a crash).
+func (s *Supervisor) runWithRestart(ctx context.Context, ns namedService) {
+	backoff := s.minBackoff
+	for {
+		err := ns.svc.Serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("service %q: Serve returned before its context was canceled", ns.name)
+		}
+		log.Printf("supervisor: %q crashed: %v (restarting in %s)", ns.name, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}